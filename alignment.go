@@ -0,0 +1,186 @@
+package elevenlabs
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Word of an utterance with absolute timing
+type WordSpan struct {
+	Word      string
+	StartMs   int
+	EndMs     int
+	CharStart int // Index of the first character in the stitched alignment
+	CharEnd   int // Index after the last character
+}
+
+// Stitches the alignment segments of one utterance into absolute timestamps.
+// Every segment restarts at 0, it is shifted to the end of the previous chunk's audio,
+// or of its last character when the audio duration is unknown.
+type AlignmentAggregator struct {
+	mu         sync.Mutex
+	alignment  StreamingAlignmentSegment
+	normalized StreamingAlignmentSegment
+	endMs      int // End of the audio added so far
+}
+
+func NewAlignmentAggregator() *AlignmentAggregator {
+	return &AlignmentAggregator{}
+}
+
+// Add a segment whose audio duration is unknown, trailing silence of the chunk is lost
+func (a *AlignmentAggregator) Add(alignment StreamingAlignmentSegment, normalized StreamingAlignmentSegment) {
+	a.AddChunk(alignment, normalized, 0)
+}
+
+// Add the segments of a chunk carrying audioMs of audio, the next chunk starts after it
+func (a *AlignmentAggregator) AddChunk(alignment StreamingAlignmentSegment, normalized StreamingAlignmentSegment, audioMs int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.alignment = appendSegment(a.alignment, alignment, a.endMs)
+	a.normalized = appendSegment(a.normalized, normalized, a.endMs)
+	a.endMs += chunkEnd(alignment, audioMs)
+}
+
+func (a *AlignmentAggregator) AddResponse(r StreamingOutputResponse) {
+	a.AddChunk(r.Alignment, r.NormalizedAlignment, r.AudioDurationMs)
+}
+
+func (a *AlignmentAggregator) AddMultiCtxResponse(r StreamingOutputMultiCtxResponse) {
+	a.AddChunk(r.Alignment, r.NormalizedAlignment, r.AudioDurationMs)
+}
+
+// Characters of the original text with absolute timing
+func (a *AlignmentAggregator) Alignment() StreamingAlignmentSegment {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return cloneSegment(a.alignment)
+}
+
+// Characters of the normalized text with absolute timing
+func (a *AlignmentAggregator) NormalizedAlignment() StreamingAlignmentSegment {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return cloneSegment(a.normalized)
+}
+
+// Words of the original text, the last one may still grow with the next segment
+func (a *AlignmentAggregator) Words() []WordSpan {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return segmentWords(a.alignment)
+}
+
+// Words of the normalized text, the last one may still grow with the next segment
+func (a *AlignmentAggregator) NormalizedWords() []WordSpan {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return segmentWords(a.normalized)
+}
+
+// Words of the original text that finished playing within the first ms of audio
+func (a *AlignmentAggregator) SpokenWords(ms int) []WordSpan {
+	words := a.Words()
+	n := 0
+	for n < len(words) && words[n].EndMs <= ms {
+		n++
+	}
+	return words[:n]
+}
+
+// Word of the original text playing at ms
+func (a *AlignmentAggregator) WordAt(ms int) (WordSpan, bool) {
+	for _, w := range a.Words() {
+		if ms >= w.StartMs && ms < w.EndMs {
+			return w, true
+		}
+	}
+	return WordSpan{}, false
+}
+
+// End of the audio added so far in ms
+func (a *AlignmentAggregator) DurationMs() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.endMs
+}
+
+func (a *AlignmentAggregator) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.alignment = StreamingAlignmentSegment{}
+	a.normalized = StreamingAlignmentSegment{}
+	a.endMs = 0
+}
+
+// End of the last character with timings, 0 for an empty segment
+func segmentEnd(s StreamingAlignmentSegment) int {
+	n := min(len(s.Chars), len(s.CharStartTimesMs), len(s.CharDurationsMs))
+	if n == 0 {
+		return 0
+	}
+	return s.CharStartTimesMs[n-1] + s.CharDurationsMs[n-1]
+}
+
+// Length of a chunk in ms, its audio or its last character when the audio runs shorter or is unknown
+func chunkEnd(s StreamingAlignmentSegment, audioMs int) int {
+	return max(audioMs, segmentEnd(s))
+}
+
+// Append next to an absolute segment in place, shifted by offset
+func appendSegment(abs StreamingAlignmentSegment, next StreamingAlignmentSegment, offset int) StreamingAlignmentSegment {
+	n := min(len(next.Chars), len(next.CharStartTimesMs), len(next.CharDurationsMs))
+	if n == 0 {
+		return abs
+	}
+	abs.Chars = append(abs.Chars, next.Chars[:n]...)
+	abs.CharDurationsMs = append(abs.CharDurationsMs, next.CharDurationsMs[:n]...)
+	for _, start := range next.CharStartTimesMs[:n] {
+		abs.CharStartTimesMs = append(abs.CharStartTimesMs, start+offset)
+	}
+	return abs
+}
+
+func cloneSegment(s StreamingAlignmentSegment) StreamingAlignmentSegment {
+	return StreamingAlignmentSegment{
+		CharStartTimesMs: append([]int(nil), s.CharStartTimesMs...),
+		CharDurationsMs:  append([]int(nil), s.CharDurationsMs...),
+		Chars:            append([]string(nil), s.Chars...),
+	}
+}
+
+// Split on whitespace, punctuation stays with its word
+func segmentWords(s StreamingAlignmentSegment) []WordSpan {
+	var words []WordSpan
+	var word strings.Builder
+	start := -1
+	for i, c := range s.Chars {
+		if strings.TrimFunc(c, unicode.IsSpace) == "" {
+			if start >= 0 {
+				words = append(words, newWordSpan(s, word.String(), start, i))
+				word.Reset()
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		word.WriteString(c)
+	}
+	if start >= 0 {
+		words = append(words, newWordSpan(s, word.String(), start, len(s.Chars)))
+	}
+	return words
+}
+
+func newWordSpan(s StreamingAlignmentSegment, word string, start int, end int) WordSpan {
+	return WordSpan{
+		Word:      word,
+		StartMs:   s.CharStartTimesMs[start],
+		EndMs:     s.CharStartTimesMs[end-1] + s.CharDurationsMs[end-1],
+		CharStart: start,
+		CharEnd:   end,
+	}
+}
@@ -0,0 +1,109 @@
+package elevenlabs_test
+
+import (
+	"strings"
+	"testing"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+// Alignment for text as the server sends it, starting at 0 with ms per character
+func segment(text string, ms int) elevenlabs.StreamingAlignmentSegment {
+	var s elevenlabs.StreamingAlignmentSegment
+	for i, r := range []rune(text) {
+		s.Chars = append(s.Chars, string(r))
+		s.CharStartTimesMs = append(s.CharStartTimesMs, i*ms)
+		s.CharDurationsMs = append(s.CharDurationsMs, ms)
+	}
+	return s
+}
+
+func TestAlignmentAggregatorStitchesSegments(t *testing.T) {
+	a := elevenlabs.NewAlignmentAggregator()
+	a.Add(segment("Hello wo", 10), segment("hello wo", 10))
+	a.AddResponse(elevenlabs.StreamingOutputResponse{Alignment: segment("rld again", 10), NormalizedAlignment: segment("rld again", 10)})
+
+	if got := a.DurationMs(); got != 170 {
+		t.Errorf("DurationMs = %d, want 170", got)
+	}
+	if got := strings.Join(a.Alignment().Chars, ""); got != "Hello world again" {
+		t.Errorf("Alignment = %q", got)
+	}
+	if got := a.Alignment().CharStartTimesMs[8]; got != 80 {
+		t.Errorf("first character of the second segment starts at %d, want 80", got)
+	}
+
+	want := []elevenlabs.WordSpan{
+		{Word: "Hello", StartMs: 0, EndMs: 50, CharStart: 0, CharEnd: 5},
+		{Word: "world", StartMs: 60, EndMs: 110, CharStart: 6, CharEnd: 11},
+		{Word: "again", StartMs: 120, EndMs: 170, CharStart: 12, CharEnd: 17},
+	}
+	words := a.Words()
+	if len(words) != len(want) {
+		t.Fatalf("Words = %+v", words)
+	}
+	for i := range want {
+		if words[i] != want[i] {
+			t.Errorf("word %d = %+v, want %+v", i, words[i], want[i])
+		}
+	}
+	if got := a.NormalizedWords()[0].Word; got != "hello" {
+		t.Errorf("first normalized word = %q", got)
+	}
+}
+
+func TestAlignmentAggregatorPlayback(t *testing.T) {
+	a := elevenlabs.NewAlignmentAggregator()
+	a.AddMultiCtxResponse(elevenlabs.StreamingOutputMultiCtxResponse{Alignment: segment("one two, three.", 10)})
+
+	if got := a.SpokenWords(80); len(got) != 2 || got[1].Word != "two," {
+		t.Errorf("SpokenWords(80) = %+v", got)
+	}
+	if got := a.SpokenWords(79); len(got) != 1 {
+		t.Errorf("SpokenWords(79) = %+v", got)
+	}
+	if w, ok := a.WordAt(45); !ok || w.Word != "two," {
+		t.Errorf("WordAt(45) = %+v, %v", w, ok)
+	}
+	if _, ok := a.WordAt(35); ok {
+		t.Error("WordAt found a word in the gap between words")
+	}
+
+	a.Reset()
+	if a.DurationMs() != 0 || len(a.Words()) != 0 {
+		t.Error("Reset kept the alignment")
+	}
+}
+
+func TestAlignmentAggregatorAudioDuration(t *testing.T) {
+	a := elevenlabs.NewAlignmentAggregator()
+	// 100 ms of audio for 50 ms of characters, the rest is silence
+	a.AddChunk(segment("Hi. ", 10), elevenlabs.StreamingAlignmentSegment{}, 100)
+	a.AddResponse(elevenlabs.StreamingOutputResponse{Alignment: segment("Bye", 10), AudioDurationMs: 20})
+
+	if got := a.Alignment().CharStartTimesMs[4]; got != 100 {
+		t.Errorf("second chunk starts at %d, want 100", got)
+	}
+	// Audio shorter than the characters does not pull the end back
+	if got := a.DurationMs(); got != 130 {
+		t.Errorf("DurationMs = %d, want 130", got)
+	}
+	if w, ok := a.WordAt(110); !ok || w.Word != "Bye" {
+		t.Errorf("WordAt(110) = %+v, %v", w, ok)
+	}
+}
+
+func TestAlignmentAggregatorIgnoresInconsistentSegments(t *testing.T) {
+	a := elevenlabs.NewAlignmentAggregator()
+	bad := segment("abc", 10)
+	bad.CharDurationsMs = bad.CharDurationsMs[:2]
+	a.Add(bad, elevenlabs.StreamingAlignmentSegment{})
+	a.Add(segment("d", 10), elevenlabs.StreamingAlignmentSegment{})
+
+	if got := strings.Join(a.Alignment().Chars, ""); got != "abd" {
+		t.Errorf("Alignment = %q, want the characters with timings", got)
+	}
+	if got := a.DurationMs(); got != 30 {
+		t.Errorf("DurationMs = %d, want 30", got)
+	}
+}
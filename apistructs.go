@@ -339,17 +339,20 @@ type GetVoiceVoiceVerification struct {
 	VerificationAttempts      []GetVoiceVerificationAttempt `json:"verification_attempts"`
 }
 type TextToSpeechInputMultiStreamingRequest struct {
-	ContextID        string            `json:"context_id,omitempty"`
-	CloseContext     bool              `json:"close_context,omitempty"`
-	CloseSocket      bool              `json:"close_socket,omitempty"`
-	Text             string            `json:"text,omitempty"`
-	Flush            bool              `json:"flush,omitempty"`
-	VoiceSettings    *VoiceSettings    `json:"voice_settings,omitempty"`
-	GenerationConfig *GenerationConfig `json:"generation_config,omitempty"`
-	//PronunciationDictionaryLocators PronunciationDictionaryLocators `json:"pronunciation_dictionary_locators,omitempty"`
+	ContextID                       string                            `json:"context_id,omitempty"`
+	CloseContext                    bool                              `json:"close_context,omitempty"`
+	CloseSocket                     bool                              `json:"close_socket,omitempty"`
+	Text                            string                            `json:"text,omitempty"`
+	Flush                           bool                              `json:"flush,omitempty"`
+	VoiceSettings                   *VoiceSettings                    `json:"voice_settings,omitempty"`
+	GenerationConfig                *GenerationConfig                 `json:"generation_config,omitempty"`
+	TryTriggerGeneration            bool                              `json:"try_trigger_generation,omitempty"`
+	PronunciationDictionaryLocators []PronunciationDictionaryLocators `json:"pronunciation_dictionary_locators,omitempty"`
+
+	mergedVoice bool // VoiceSettings merged from overrides, sent with every field
 }
 
 type PronunciationDictionaryLocators struct {
-	DictionaryId string `json:"dictionary_id"`
-	VersionId    string `json:"version_id"`
+	DictionaryId string `json:"pronunciation_dictionary_id"`
+	VersionId    string `json:"version_id,omitempty"` // Latest version when empty
 }
@@ -0,0 +1,281 @@
+// Package cache keeps synthesized audio and alignment so repeated prompts are not paid for twice.
+// Hits are replayed through the same AudioResponsePipe and alignment channel as a live stream.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	neturl "net/url"
+	"sync/atomic"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+// Returned by Store.Get for unknown keys
+var ErrNotFound = errors.New("cache: entry not found")
+
+// Entry storage, implementations must be safe for concurrent use
+type Store interface {
+	Get(key string) (*Entry, error)
+	Put(key string, e *Entry) error
+	Delete(key string) error
+}
+
+// One recorded event of a stream, exactly one of Audio, Alignment or Boundary is set
+type Frame struct {
+	At        time.Duration // Since the first frame
+	Audio     []byte
+	Alignment *elevenlabs.StreamingOutputResponse
+	Boundary  bool
+}
+
+type Entry struct {
+	Format  elevenlabs.AudioFormatInfo
+	Frames  []Frame
+	Created time.Time
+}
+
+// Audio bytes held by the entry
+func (e *Entry) Size() int {
+	n := 0
+	for _, f := range e.Frames {
+		n += len(f.Audio)
+	}
+	return n
+}
+
+func (e *Entry) Audio() []byte {
+	audio := make([]byte, 0, e.Size())
+	for _, f := range e.Frames {
+		audio = append(audio, f.Audio...)
+	}
+	return audio
+}
+
+// Alignment of the whole entry with absolute timing
+func (e *Entry) Alignment() *elevenlabs.AlignmentAggregator {
+	var a elevenlabs.AlignmentAggregator
+	for _, f := range e.Frames {
+		if f.Alignment != nil {
+			a.AddResponse(*f.Alignment)
+		}
+	}
+	return &a
+}
+
+// Everything that changes the synthesized audio
+type keyInput struct {
+	Text          string                                       `json:"text"`
+	VoiceID       string                                       `json:"voice_id"`
+	ModelID       string                                       `json:"model_id"`
+	VoiceSettings *elevenlabs.VoiceSettings                    `json:"voice_settings"`
+	Format        elevenlabs.AudioFormat                       `json:"format"`
+	Locators      []elevenlabs.PronunciationDictionaryLocators `json:"locators"`
+	Query         string                                       `json:"query"` // Other queries, e.g. language_code
+
+	// HTTP request fields
+	LanguageCode string `json:"language_code,omitempty"`
+	Seed         int    `json:"seed,omitempty"`
+	PreviousText string `json:"previous_text,omitempty"`
+	NextText     string `json:"next_text,omitempty"`
+}
+
+// Hex SHA-256 of the text, voice, model, settings, output format, dictionaries and queries
+func Key(text string, voiceID string, modelID string, settings *elevenlabs.VoiceSettings, locators []elevenlabs.PronunciationDictionaryLocators, queries ...elevenlabs.QueryFunc) (string, error) {
+	return key(keyInput{Text: text, VoiceID: voiceID, ModelID: modelID, VoiceSettings: settings, Locators: locators}, queries)
+}
+
+// Key of an HTTP text-to-speech request, every request field that changes the audio included
+func TextToSpeechKey(voiceID string, modelID string, req elevenlabs.TextToSpeechRequest, locators []elevenlabs.PronunciationDictionaryLocators, queries ...elevenlabs.QueryFunc) (string, error) {
+	return key(keyInput{
+		Text:          req.Text,
+		VoiceID:       voiceID,
+		ModelID:       modelID,
+		VoiceSettings: req.VoiceSettings,
+		Locators:      locators,
+		LanguageCode:  req.LanguageCode,
+		Seed:          req.Seed,
+		PreviousText:  req.PreviousText,
+		NextText:      req.NextText,
+	}, queries)
+}
+
+func key(in keyInput, queries []elevenlabs.QueryFunc) (string, error) {
+	format, err := elevenlabs.ResolveAudioFormat(queries...)
+	if err != nil {
+		return "", err
+	}
+	q := neturl.Values{}
+	for _, qf := range queries {
+		qf(&q)
+	}
+	q.Del("output_format")
+	in.Format, in.Query = format.Format, q.Encode()
+
+	b, err := json.Marshal(in)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type Option func(*Cache)
+
+// Entries older than ttl are treated as misses and deleted, 0 keeps them forever
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// Replay hits at the recorded cadence, enabled by default. Disabled, hits are written as fast as the consumer reads.
+func WithPacing(paced bool) Option {
+	return func(c *Cache) {
+		c.paced = paced
+	}
+}
+
+// Store failures are logged here, they never fail a request
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Cache) {
+		c.logger = logger
+	}
+}
+
+// Client front serving repeated requests from a Store
+type Cache struct {
+	ctx    context.Context
+	client *elevenlabs.Client
+	store  Store
+	ttl    time.Duration
+	paced  bool
+	logger *slog.Logger
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+func New(ctx context.Context, client *elevenlabs.Client, store Store, opts ...Option) *Cache {
+	c := &Cache{ctx: ctx, client: client, store: store, paced: true}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Cache) Stats() (hits uint64, misses uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// Fresh entry for key, nil on a miss
+func (c *Cache) lookup(key string) *Entry {
+	e, err := c.store.Get(key)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		c.warn("cache read failed", key, err)
+	case c.ttl > 0 && time.Since(e.Created) > c.ttl:
+		if err := c.store.Delete(key); err != nil {
+			c.warn("cache delete failed", key, err)
+		}
+	default:
+		c.hits.Add(1)
+		return e
+	}
+	c.misses.Add(1)
+	return nil
+}
+
+func (c *Cache) save(key string, e *Entry) {
+	if len(e.Frames) == 0 {
+		return
+	}
+	e.Created = time.Now()
+	if err := c.store.Put(key, e); err != nil {
+		c.warn("cache write failed", key, err)
+	}
+}
+
+func (c *Cache) warn(msg string, key string, err error) {
+	if c.logger != nil {
+		c.logger.Warn(msg, "key", key, "error", err)
+	}
+}
+
+// StreamingRequest for a complete prompt. Misses are synthesized over the websocket and recorded,
+// hits are replayed through AudioResponsePipe and AlignmentResponseChannel.
+func (c *Cache) StreamingRequest(text string, AlignmentResponseChannel chan elevenlabs.StreamingOutputResponse, AudioResponsePipe io.Writer, voiceID string, modelID string, req elevenlabs.TextToSpeechInputStreamingRequest, queries ...elevenlabs.QueryFunc) error {
+	locators := req.PronunciationDictionaryLocators
+	if locators == nil {
+		locators = c.client.DefaultDictionaries()
+	}
+	key, err := Key(text, voiceID, modelID, req.VoiceSettings, locators, queries...)
+	if err != nil {
+		return err
+	}
+	if e := c.lookup(key); e != nil {
+		// Hits follow the client's delivery policy like a live stream
+		forward := c.client.ForwardAlignment(AlignmentResponseChannel)
+		defer forward.Close()
+		return replay(c.ctx, e, func(resp elevenlabs.StreamingOutputResponse) bool { return forward.Send(c.ctx, resp) }, AudioResponsePipe, c.paced)
+	}
+
+	input := make(chan elevenlabs.InputEvent, 3)
+	input <- elevenlabs.TextInput(text)
+	input <- elevenlabs.FlushInput()
+	input <- elevenlabs.CloseSocketInput()
+	close(input)
+
+	// Alignment is always recorded, isFinal marks a complete stream
+	rec := newRecorder(AudioResponsePipe)
+	err = c.client.StreamingRequestEvents(input, AlignmentResponseChannel, rec, voiceID, modelID, req, queries...)
+	if err != nil {
+		return err
+	}
+	if rec.final() && c.ctx.Err() == nil {
+		c.save(key, rec.entry())
+	}
+	return nil
+}
+
+// TextToSpeech through the with-timestamps endpoint, so hits carry alignment too. Meta is nil for hits.
+func (c *Cache) TextToSpeech(voiceID string, modelID string, req elevenlabs.TextToSpeechRequest, queries ...elevenlabs.QueryFunc) (*elevenlabs.TextToSpeechResponse, error) {
+	locators := req.PronunciationDictionaryLocators
+	if locators == nil {
+		locators = c.client.DefaultDictionaries()
+	}
+	key, err := TextToSpeechKey(voiceID, modelID, req, locators, queries...)
+	if err != nil {
+		return nil, err
+	}
+	if e := c.lookup(key); e != nil {
+		a := e.Alignment()
+		return &elevenlabs.TextToSpeechResponse{
+			Audio:               io.NopCloser(bytes.NewReader(e.Audio())),
+			Format:              e.Format,
+			Alignment:           a.Alignment(),
+			NormalizedAlignment: a.NormalizedAlignment(),
+		}, nil
+	}
+
+	res, err := c.client.TextToSpeechWithTimestamps(voiceID, modelID, req, queries...)
+	if err != nil {
+		return nil, err
+	}
+	audio, err := io.ReadAll(res.Audio)
+	res.Audio.Close()
+	if err != nil {
+		return nil, err
+	}
+	output := res.Output()
+	c.save(key, &Entry{Format: res.Format, Frames: []Frame{{Audio: audio}, {Alignment: &output}, {Boundary: true}}})
+	res.Audio = io.NopCloser(bytes.NewReader(audio))
+	return res, nil
+}
@@ -0,0 +1,247 @@
+package cache_test
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/clearlyip/elevenlabs-go-websockets/cache"
+	"github.com/clearlyip/elevenlabs-go-websockets/elevenlabstest"
+)
+
+// Audio and alignment received by one request
+type received struct {
+	audio     []byte
+	alignment []elevenlabs.StreamingOutputResponse
+}
+
+func request(t *testing.T, c *cache.Cache, text string, queries ...elevenlabs.QueryFunc) received {
+	t.Helper()
+	var audio bytes.Buffer
+	ch := make(chan elevenlabs.StreamingOutputResponse, 32)
+	if err := c.StreamingRequest(text, ch, &audio, "voice", "model", elevenlabs.TextToSpeechInputStreamingRequest{}, queries...); err != nil {
+		t.Fatalf("StreamingRequest = %v", err)
+	}
+	close(ch)
+	r := received{audio: audio.Bytes()}
+	for resp := range ch {
+		r.alignment = append(r.alignment, resp)
+	}
+	return r
+}
+
+func TestCacheReplaysHits(t *testing.T) {
+	dir, err := cache.NewDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, store := range map[string]cache.Store{"memory": cache.NewMemory(0), "dir": dir} {
+		t.Run(name, func(t *testing.T) {
+			srv := elevenlabstest.NewServer()
+			defer srv.Close()
+			client := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+			c := cache.New(context.Background(), client, store, cache.WithPacing(false))
+
+			miss := request(t, c, "Hello there.", elevenlabs.FormatPCM_16000.Query())
+			hit := request(t, c, "Hello there.", elevenlabs.FormatPCM_16000.Query())
+
+			if string(miss.audio) != "Hello there." || !bytes.Equal(miss.audio, hit.audio) {
+				t.Errorf("audio = %q, then %q", miss.audio, hit.audio)
+			}
+			if len(hit.alignment) != len(miss.alignment) || !hit.alignment[len(hit.alignment)-1].IsFinal {
+				t.Errorf("alignment = %+v, then %+v", miss.alignment, hit.alignment)
+			}
+			if n := srv.Connections(); n != 1 {
+				t.Errorf("connections = %d, the hit was synthesized again", n)
+			}
+			if hits, misses := c.Stats(); hits != 1 || misses != 1 {
+				t.Errorf("Stats = %d hits, %d misses", hits, misses)
+			}
+
+			key, _ := cache.Key("Hello there.", "voice", "model", nil, nil, elevenlabs.FormatPCM_16000.Query())
+			e, err := store.Get(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Format.Format != elevenlabs.FormatPCM_16000 || strings.Join(e.Alignment().Alignment().Chars, "") != "Hello there." {
+				t.Errorf("entry format %s, alignment %q", e.Format.Format, e.Alignment().Alignment().Chars)
+			}
+		})
+	}
+}
+
+func TestCacheFollowsDeliveryPolicy(t *testing.T) {
+	srv := elevenlabstest.NewServer()
+	defer srv.Close()
+	client := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()),
+		elevenlabs.WithAlignmentDelivery(elevenlabs.DeliverDropNewest))
+	store := cache.NewMemory(0)
+	c := cache.New(context.Background(), client, store, cache.WithPacing(false))
+
+	// Nobody reads the alignment channel, the miss and the hit drop instead of blocking
+	for range 2 {
+		done := make(chan error, 1)
+		go func() {
+			done <- c.StreamingRequest("Hello there.", make(chan elevenlabs.StreamingOutputResponse), io.Discard, "voice", "model", elevenlabs.TextToSpeechInputStreamingRequest{})
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("StreamingRequest = %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("StreamingRequest blocked on an undrained alignment channel")
+		}
+	}
+	if n := srv.Connections(); n != 1 {
+		t.Errorf("connections = %d, the dropped events kept the stream from being cached", n)
+	}
+	if client.DroppedAlignments() == 0 {
+		t.Error("no dropped alignment events counted")
+	}
+
+	// Dropped events are still recorded
+	key, _ := cache.Key("Hello there.", "voice", "model", nil, nil)
+	e, err := store.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(e.Alignment().Alignment().Chars, ""); got != "Hello there." {
+		t.Errorf("recorded alignment %q", got)
+	}
+}
+
+func TestCacheSkipsIncompleteStreams(t *testing.T) {
+	srv := elevenlabstest.NewServer(elevenlabstest.WithFaults(elevenlabstest.Delay(0, 200*time.Millisecond)))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	client := elevenlabs.NewClient(ctx, "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+	store := cache.NewMemory(0)
+
+	err := cache.New(ctx, client, store).StreamingRequest("Hello there.", nil, io.Discard, "voice", "model", elevenlabs.TextToSpeechInputStreamingRequest{})
+	if err == nil {
+		t.Error("cancelled StreamingRequest succeeded")
+	}
+	if n := store.Len(); n != 0 {
+		t.Errorf("%d entries stored for a cancelled stream", n)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	store := cache.NewMemory(0)
+	key, _ := cache.Key("Hi", "voice", "model", nil, nil)
+	store.Put(key, &cache.Entry{Frames: []cache.Frame{{Audio: []byte("old")}}, Created: time.Now().Add(-time.Hour)})
+
+	srv := elevenlabstest.NewServer()
+	defer srv.Close()
+	client := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+	c := cache.New(context.Background(), client, store, cache.WithTTL(time.Minute), cache.WithPacing(false))
+
+	if got := request(t, c, "Hi"); string(got.audio) != "Hi" {
+		t.Errorf("audio = %q, the expired entry was served", got.audio)
+	}
+	if hits, misses := c.Stats(); hits != 0 || misses != 1 {
+		t.Errorf("Stats = %d hits, %d misses", hits, misses)
+	}
+}
+
+func TestKey(t *testing.T) {
+	base, err := cache.TextToSpeechKey("voice", "model", elevenlabs.TextToSpeechRequest{Text: "Hi"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The default output format is the same audio as no output format
+	same, _ := cache.TextToSpeechKey("voice", "model", elevenlabs.TextToSpeechRequest{Text: "Hi"}, nil, elevenlabs.DEFAULT_OUTPUT_FORMAT.Query())
+	if same != base {
+		t.Error("explicit default format changes the key")
+	}
+
+	variants := []struct {
+		name     string
+		voice    string
+		model    string
+		req      elevenlabs.TextToSpeechRequest
+		locators []elevenlabs.PronunciationDictionaryLocators
+		queries  []elevenlabs.QueryFunc
+	}{
+		{name: "text", req: elevenlabs.TextToSpeechRequest{Text: "Hey"}},
+		{name: "voice", voice: "other"},
+		{name: "model", model: "other"},
+		{name: "language", req: elevenlabs.TextToSpeechRequest{LanguageCode: "de"}},
+		{name: "seed", req: elevenlabs.TextToSpeechRequest{Seed: 7}},
+		{name: "previous", req: elevenlabs.TextToSpeechRequest{PreviousText: "a"}},
+		{name: "next", req: elevenlabs.TextToSpeechRequest{NextText: "b"}},
+		{name: "settings", req: elevenlabs.TextToSpeechRequest{VoiceSettings: &elevenlabs.VoiceSettings{Stability: 0.5}}},
+		{name: "locators", locators: []elevenlabs.PronunciationDictionaryLocators{{DictionaryId: "d"}}},
+		{name: "format", queries: []elevenlabs.QueryFunc{elevenlabs.FormatULAW_8000.Query()}},
+		{name: "query", queries: []elevenlabs.QueryFunc{elevenlabs.EnableSsmlParsing("true")}},
+	}
+	for _, v := range variants {
+		// Unset fields fall back to the base request
+		voice, model := cmp.Or(v.voice, "voice"), cmp.Or(v.model, "model")
+		v.req.Text = cmp.Or(v.req.Text, "Hi")
+		key, err := cache.TextToSpeechKey(voice, model, v.req, v.locators, v.queries...)
+		if err != nil {
+			t.Fatalf("%s: %v", v.name, err)
+		}
+		if key == base {
+			t.Errorf("%s does not change the key", v.name)
+		}
+	}
+
+	if _, err := cache.Key("Hi", "voice", "model", nil, nil, elevenlabs.OutputFormat("wav_1")); err == nil {
+		t.Error("Key accepted an unknown output format")
+	}
+}
+
+func TestCacheTextToSpeech(t *testing.T) {
+	var calls atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !strings.HasSuffix(r.URL.Path, "/with-timestamps") {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"audio_base64": base64.StdEncoding.EncodeToString([]byte("clip")),
+			"alignment": map[string]any{
+				"characters":                    []string{"H", "i"},
+				"character_start_times_seconds": []float64{0, 0.1},
+				"character_end_times_seconds":   []float64{0.1, 0.25},
+			},
+		})
+	}))
+	defer api.Close()
+	client := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithBaseURL(api.URL))
+	c := cache.New(context.Background(), client, cache.NewMemory(0))
+
+	for i := range 2 {
+		res, err := c.TextToSpeech("voice", "model", elevenlabs.TextToSpeechRequest{Text: "Hi"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		audio, _ := io.ReadAll(res.Audio)
+		res.Audio.Close()
+		if string(audio) != "clip" || len(res.Alignment.Chars) != 2 || res.Alignment.CharDurationsMs[1] != 150 {
+			t.Errorf("request %d: audio %q, alignment %+v", i, audio, res.Alignment)
+		}
+		if (res.Meta == nil) != (i == 1) {
+			t.Errorf("request %d: Meta = %+v, only hits have none", i, res.Meta)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("%d API calls", n)
+	}
+}
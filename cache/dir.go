@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Filesystem Store keeping one gob file per key, shared by processes using the same directory
+type Dir struct {
+	path string
+}
+
+func NewDir(path string) (*Dir, error) {
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	return &Dir{path: path}, nil
+}
+
+func (d *Dir) file(key string) string {
+	return filepath.Join(d.path, key+".gob")
+}
+
+func (d *Dir) Get(key string) (*Entry, error) {
+	f, err := os.Open(d.file(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var e Entry
+	if err := gob.NewDecoder(f).Decode(&e); err != nil {
+		return nil, fmt.Errorf("decoding cache entry %s: %w", key, err)
+	}
+	return &e, nil
+}
+
+// Written to a temporary file and renamed, readers never see partial entries
+func (d *Dir) Put(key string, e *Entry) error {
+	f, err := os.CreateTemp(d.path, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := gob.NewEncoder(f).Encode(e); err != nil {
+		f.Close()
+		return fmt.Errorf("encoding cache entry %s: %w", key, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), d.file(key))
+}
+
+func (d *Dir) Delete(key string) error {
+	err := os.Remove(d.file(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// In-memory Store evicting the least recently used entries beyond its audio byte budget
+type Memory struct {
+	mu       sync.Mutex
+	maxBytes int
+	size     int
+	order    *list.List // Front is most recently used
+	entries  map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	entry *Entry
+}
+
+// maxBytes bounds the stored audio, 0 means unbounded
+func NewMemory(maxBytes int) *Memory {
+	return &Memory{maxBytes: maxBytes, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (m *Memory) Get(key string) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	m.order.MoveToFront(el)
+	return el.Value.(*memoryItem).entry, nil
+}
+
+func (m *Memory) Put(key string, e *Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(key)
+	m.entries[key] = m.order.PushFront(&memoryItem{key: key, entry: e})
+	m.size += e.Size()
+
+	// The newest entry stays even when it alone exceeds the budget
+	for m.maxBytes > 0 && m.size > m.maxBytes && m.order.Len() > 1 {
+		m.remove(m.order.Back().Value.(*memoryItem).key)
+	}
+	return nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(key)
+	return nil
+}
+
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+func (m *Memory) remove(key string) {
+	el, ok := m.entries[key]
+	if !ok {
+		return
+	}
+	m.order.Remove(el)
+	delete(m.entries, key)
+	m.size -= el.Value.(*memoryItem).entry.Size()
+}
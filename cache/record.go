@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+// AudioResponsePipe wrapper recording the stream while passing it on
+type recorder struct {
+	w       io.Writer
+	mu      sync.Mutex
+	start   time.Time
+	format  elevenlabs.AudioFormatInfo
+	frames  []Frame
+	isFinal bool // The generation was completed
+}
+
+func newRecorder(w io.Writer) *recorder {
+	return &recorder{w: w}
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.add(Frame{Audio: append([]byte(nil), p...)})
+	return r.w.Write(p)
+}
+
+func (r *recorder) SetAudioFormat(info elevenlabs.AudioFormatInfo) {
+	r.mu.Lock()
+	r.format = info
+	r.mu.Unlock()
+	if fr, ok := r.w.(elevenlabs.AudioFormatReceiver); ok {
+		fr.SetAudioFormat(info)
+	}
+}
+
+func (r *recorder) AudioBoundary() {
+	r.add(Frame{Boundary: true})
+	if br, ok := r.w.(elevenlabs.AudioBoundaryReceiver); ok {
+		br.AudioBoundary()
+	}
+}
+
+// Every event is recorded, whatever the client's delivery policy drops from the alignment channel
+func (r *recorder) AudioAlignment(resp elevenlabs.StreamingOutputResponse) {
+	r.add(Frame{Alignment: &resp})
+	if resp.IsFinal {
+		r.mu.Lock()
+		r.isFinal = true
+		r.mu.Unlock()
+	}
+	if ar, ok := r.w.(elevenlabs.AudioAlignmentReceiver); ok {
+		ar.AudioAlignment(resp)
+	}
+}
+
+func (r *recorder) final() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.isFinal
+}
+
+func (r *recorder) add(f Frame) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if r.start.IsZero() {
+		r.start = now
+	}
+	f.At = now.Sub(r.start)
+	r.frames = append(r.frames, f)
+}
+
+func (r *recorder) entry() *Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Entry{Format: r.format, Frames: r.frames}
+}
+
+// Write an entry to audio and alignment the way the streaming request delivered it.
+// Paced, frames keep their recorded spacing. A nil alignment channel or audio writer skips that part.
+func Replay(ctx context.Context, e *Entry, alignment chan<- elevenlabs.StreamingOutputResponse, audio io.Writer, paced bool) error {
+	var send func(elevenlabs.StreamingOutputResponse) bool
+	if alignment != nil {
+		send = func(resp elevenlabs.StreamingOutputResponse) bool {
+			select {
+			case alignment <- resp:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+	}
+	return replay(ctx, e, send, audio, paced)
+}
+
+// Replay handing alignment to send, false stops the replay. A nil send skips alignment.
+func replay(ctx context.Context, e *Entry, send func(elevenlabs.StreamingOutputResponse) bool, audio io.Writer, paced bool) error {
+	if fr, ok := audio.(elevenlabs.AudioFormatReceiver); ok && e.Format.Format != "" {
+		fr.SetAudioFormat(e.Format)
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for _, f := range e.Frames {
+		if wait := time.Until(start.Add(f.At)); paced && wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		switch {
+		case f.Audio != nil:
+			if audio == nil {
+				continue
+			}
+			if _, err := audio.Write(f.Audio); err != nil {
+				return err
+			}
+		case f.Alignment != nil:
+			if ar, ok := audio.(elevenlabs.AudioAlignmentReceiver); ok {
+				ar.AudioAlignment(*f.Alignment)
+			}
+			if send != nil && !send(*f.Alignment) {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				return context.Canceled
+			}
+		case f.Boundary:
+			if br, ok := audio.(elevenlabs.AudioBoundaryReceiver); ok {
+				br.AudioBoundary()
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/clearlyip/elevenlabs-go-websockets/cache"
+)
+
+func entry(audio string) *cache.Entry {
+	return &cache.Entry{Frames: []cache.Frame{{Audio: []byte(audio)}}}
+}
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	m := cache.NewMemory(10)
+	m.Put("a", entry("aaaa"))
+	m.Put("b", entry("bbbb"))
+	m.Get("a")
+	m.Put("c", entry("cccc"))
+
+	if _, err := m.Get("b"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("least recently used entry kept: %v", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, err := m.Get(key); err != nil {
+			t.Errorf("Get(%s) = %v", key, err)
+		}
+	}
+
+	// An entry over the budget replaces everything else but is kept
+	m.Put("big", entry("0123456789abc"))
+	if m.Len() != 1 {
+		t.Errorf("Len = %d, want 1", m.Len())
+	}
+	m.Delete("big")
+	if m.Len() != 0 {
+		t.Errorf("Len after Delete = %d", m.Len())
+	}
+}
+
+func TestDir(t *testing.T) {
+	d, err := cache.NewDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Get("missing"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get of a missing key = %v", err)
+	}
+
+	created := time.Now().Round(0)
+	e := &cache.Entry{
+		Format:  elevenlabs.AudioFormatInfo{Format: elevenlabs.FormatULAW_8000, Encoding: elevenlabs.EncodingULAW, SampleRate: 8000, Channels: 1, BitsPerSample: 8},
+		Frames:  []cache.Frame{{Audio: []byte("ab")}, {At: time.Second, Alignment: &elevenlabs.StreamingOutputResponse{IsFinal: true}}, {Boundary: true}},
+		Created: created,
+	}
+	if err := d.Put("key", e); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.Get("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Format != e.Format || !got.Created.Equal(created) || len(got.Frames) != 3 || string(got.Frames[0].Audio) != "ab" ||
+		got.Frames[1].At != time.Second || !got.Frames[1].Alignment.IsFinal || !got.Frames[2].Boundary {
+		t.Errorf("entry = %+v", got)
+	}
+
+	if err := d.Delete("key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Delete("key"); err != nil {
+		t.Errorf("Delete of a missing key = %v", err)
+	}
+}
+
+// Writer observing the format and boundaries a replay reports
+type pipe struct {
+	events []string
+}
+
+func (p *pipe) Write(b []byte) (int, error) {
+	p.events = append(p.events, string(b))
+	return len(b), nil
+}
+
+func (p *pipe) SetAudioFormat(info elevenlabs.AudioFormatInfo) {
+	p.events = append(p.events, "format "+string(info.Format))
+}
+
+func (p *pipe) AudioBoundary() {
+	p.events = append(p.events, "|")
+}
+
+func TestReplay(t *testing.T) {
+	e := &cache.Entry{
+		Format: elevenlabs.AudioFormatInfo{Format: elevenlabs.FormatPCM_16000},
+		Frames: []cache.Frame{
+			{Audio: []byte("one")},
+			{At: 30 * time.Millisecond, Alignment: &elevenlabs.StreamingOutputResponse{}},
+			{At: 60 * time.Millisecond, Audio: []byte("two")},
+			{At: 60 * time.Millisecond, Boundary: true},
+		},
+	}
+
+	var p pipe
+	alignment := make(chan elevenlabs.StreamingOutputResponse, 1)
+	start := time.Now()
+	if err := cache.Replay(context.Background(), e, alignment, &p, true); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("paced replay took %v", elapsed)
+	}
+	if got := len(alignment); got != 1 {
+		t.Errorf("%d alignment events", got)
+	}
+	want := []string{"format pcm_16000", "one", "two", "|"}
+	if len(p.events) != len(want) {
+		t.Fatalf("events = %q", p.events)
+	}
+	for i := range want {
+		if p.events[i] != want[i] {
+			t.Errorf("events = %q, want %q", p.events, want)
+			break
+		}
+	}
+
+	// A blocked alignment channel gives way to cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := cache.Replay(ctx, e, make(chan elevenlabs.StreamingOutputResponse), nil, false); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled Replay = %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package elevenlabs
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const DEFAULT_CHUNK_MIN = 20  // Runes
+const DEFAULT_CHUNK_MAX = 200 // Runes
+
+// Words ending in a period that do not end a sentence, lower case without the final period.
+// Words that are also ordinary sentence endings ("no", "co", "mar", "dec", ...) are left out.
+var DEFAULT_ABBREVIATIONS = []string{
+	"dr", "mr", "mrs", "ms", "prof", "sr", "jr", "st", "mt", "vs", "etc", "e.g", "i.e", "inc", "ltd", "corp", "approx", "dept", "jan", "feb", "apr", "jun", "jul", "aug", "sept", "oct", "nov",
+}
+
+type ChunkerOption func(*TextChunker)
+
+// Shortest chunk emitted at a sentence boundary
+func WithMinChunk(n int) ChunkerOption {
+	return func(t *TextChunker) {
+		t.min = n
+	}
+}
+
+// Longest chunk, longer text is split at the last clause boundary or space
+func WithMaxChunk(n int) ChunkerOption {
+	return func(t *TextChunker) {
+		t.max = n
+	}
+}
+
+// Emit buffered text followed by FLUSH_MARKER when no token arrived for d
+func WithIdleFlush(d time.Duration) ChunkerOption {
+	return func(t *TextChunker) {
+		t.idle = d
+	}
+}
+
+// Replace the abbreviation list, entries are matched case-insensitively without their final period
+func WithAbbreviations(abbreviations ...string) ChunkerOption {
+	return func(t *TextChunker) {
+		t.abbreviations = make(map[string]bool, len(abbreviations))
+		for _, a := range abbreviations {
+			t.abbreviations[strings.ToLower(strings.TrimSuffix(a, "."))] = true
+		}
+	}
+}
+
+// Buffers token streams, e.g. from an LLM, and emits them at sentence and clause boundaries.
+// Output is passed as TextReader, CLOSURE_MARKER and FLUSH_MARKER are forwarded after the buffered text.
+type TextChunker struct {
+	ctx           context.Context
+	in            <-chan string
+	out           chan string
+	min           int
+	max           int
+	idle          time.Duration
+	abbreviations map[string]bool
+	buf           []rune
+}
+
+// Chunker reading tokens from in until it is closed or ctx is done, Output is closed afterwards
+func NewTextChunker(ctx context.Context, in <-chan string, opts ...ChunkerOption) *TextChunker {
+	t := &TextChunker{
+		ctx: ctx,
+		in:  in,
+		out: make(chan string),
+		min: DEFAULT_CHUNK_MIN,
+		max: DEFAULT_CHUNK_MAX,
+	}
+	WithAbbreviations(DEFAULT_ABBREVIATIONS...)(t)
+	for _, opt := range opts {
+		opt(t)
+	}
+	go t.run()
+	return t
+}
+
+// Channel to pass as TextReader
+func (t *TextChunker) Output() chan string {
+	return t.out
+}
+
+func (t *TextChunker) run() {
+	defer close(t.out)
+
+	var timer *time.Timer
+	var idle <-chan time.Time
+	if t.idle > 0 {
+		timer = time.NewTimer(t.idle)
+		timer.Stop()
+		defer timer.Stop()
+	}
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+
+		case <-idle:
+			idle = nil
+			if len(t.buf) > 0 && (!t.emit(string(t.buf)) || !t.emit(FLUSH_MARKER)) {
+				return
+			}
+			t.buf = t.buf[:0]
+
+		case token, ok := <-t.in:
+			if !ok {
+				if len(t.buf) > 0 {
+					t.emit(string(t.buf))
+				}
+				return
+			}
+
+			if token == FLUSH_MARKER || token == CLOSURE_MARKER {
+				if len(t.buf) > 0 && !t.emit(string(t.buf)) {
+					return
+				}
+				t.buf = t.buf[:0]
+				if !t.emit(token) {
+					return
+				}
+				continue
+			}
+
+			t.buf = append(t.buf, []rune(token)...)
+			for {
+				n := t.split()
+				if n == 0 {
+					break
+				}
+				if !t.emit(string(t.buf[:n])) {
+					return
+				}
+				t.buf = append(t.buf[:0], t.buf[n:]...)
+			}
+
+			if timer != nil {
+				timer.Reset(t.idle)
+				idle = timer.C
+			}
+		}
+	}
+}
+
+func (t *TextChunker) emit(chunk string) bool {
+	select {
+	case t.out <- chunk:
+		return true
+	case <-t.ctx.Done():
+		return false
+	}
+}
+
+// Length of the next chunk in the buffer, 0 to wait for more text
+func (t *TextChunker) split() int {
+	var clause, sentence, space int
+	for i := 0; i < len(t.buf); i++ {
+		if i >= t.max {
+			break
+		}
+		r := t.buf[i]
+		end := 0
+		switch {
+		case r == '\n':
+			end = i + 1
+		case strings.ContainsRune("。！？", r):
+			end = t.skipSpace(i + 1)
+		case strings.ContainsRune(".!?…", r):
+			if j, ok := t.followedBySpace(i); ok && !(r == '.' && t.abbreviation(i)) {
+				end = j
+			}
+		case strings.ContainsRune("、，；：", r):
+			clause = t.skipSpace(i + 1)
+		case strings.ContainsRune(",;:", r):
+			if j, ok := t.followedBySpace(i); ok {
+				clause = j
+			}
+		case unicode.IsSpace(r):
+			space = i + 1
+		}
+
+		if end > 0 {
+			if end >= t.min {
+				return end
+			}
+			sentence = end
+		}
+	}
+
+	if len(t.buf) <= t.max {
+		return 0
+	}
+	switch {
+	case max(clause, sentence) > 0 && max(clause, sentence) <= t.max:
+		return max(clause, sentence)
+	case space > 0:
+		return space
+	}
+	return t.max
+}
+
+// Index after the whitespace following the punctuation at i, closing quotes and brackets may come in between
+func (t *TextChunker) followedBySpace(i int) (int, bool) {
+	j := i + 1
+	for j < len(t.buf) && strings.ContainsRune("\"')]»”’", t.buf[j]) {
+		j++
+	}
+	if j >= len(t.buf) || !unicode.IsSpace(t.buf[j]) {
+		return 0, false
+	}
+	return t.skipSpace(j), true
+}
+
+func (t *TextChunker) skipSpace(i int) int {
+	for i < len(t.buf) && unicode.IsSpace(t.buf[i]) && t.buf[i] != '\n' {
+		i++
+	}
+	return i
+}
+
+// Whether the period at i ends an abbreviation or a single letter initial
+func (t *TextChunker) abbreviation(i int) bool {
+	start := i
+	for start > 0 && (unicode.IsLetter(t.buf[start-1]) || t.buf[start-1] == '.') {
+		start--
+	}
+	word := string(t.buf[start:i])
+	if word == "" {
+		return false
+	}
+	if r := []rune(word); len(r) == 1 && unicode.IsUpper(r[0]) {
+		return true
+	}
+	return t.abbreviations[strings.ToLower(word)]
+}
@@ -0,0 +1,128 @@
+package elevenlabs_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+// Chunks emitted for tokens, the input is closed after the last one
+func chunks(t *testing.T, tokens []string, opts ...elevenlabs.ChunkerOption) []string {
+	t.Helper()
+	in := make(chan string, len(tokens))
+	for _, token := range tokens {
+		in <- token
+	}
+	close(in)
+
+	var out []string
+	c := elevenlabs.NewTextChunker(context.Background(), in, opts...)
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case chunk, ok := <-c.Output():
+			if !ok {
+				return out
+			}
+			out = append(out, chunk)
+		case <-timeout:
+			t.Fatalf("output not closed, got %q", out)
+		}
+	}
+}
+
+func TestTextChunkerSentences(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []string
+		want   []string
+	}{
+		{"abbreviations", []string{"I said no", ". Then Dr", ". Smith left. ", "Bye"}, []string{"I said no. ", "Then Dr. Smith left. ", "Bye"}},
+		{"initials", []string{"J. R. R. Tolkien wrote it. Fine."}, []string{"J. R. R. Tolkien wrote it. ", "Fine."}},
+		{"quotes", []string{`"Stop!" she said. `, "Ok"}, []string{`"Stop!" `, "she said. ", "Ok"}},
+		{"decimals", []string{"Pi is 3.14 today. Yes"}, []string{"Pi is 3.14 today. ", "Yes"}},
+		{"newline", []string{"one\ntwo"}, []string{"one\n", "two"}},
+		{"cjk", []string{"你好。再见。"}, []string{"你好。", "再见。"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunks(t, tt.tokens, elevenlabs.WithMinChunk(1)); !slices.Equal(got, tt.want) {
+				t.Errorf("chunks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextChunkerMinChunk(t *testing.T) {
+	got := chunks(t, []string{"Hi. ", "Yes. ", "A longer sentence here. ", "End"})
+	want := []string{"Hi. Yes. A longer sentence here. ", "End"}
+	if !slices.Equal(got, want) {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+}
+
+func TestTextChunkerMaxChunk(t *testing.T) {
+	got := chunks(t, []string{"first part, second part and more words"}, elevenlabs.WithMaxChunk(16), elevenlabs.WithMinChunk(1))
+	want := []string{"first part, ", "second part and ", "more words"}
+	if !slices.Equal(got, want) {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+
+	got = chunks(t, []string{"abcdefghij"}, elevenlabs.WithMaxChunk(4))
+	if want := []string{"abcd", "efgh", "ij"}; !slices.Equal(got, want) {
+		t.Errorf("chunks without spaces = %q, want %q", got, want)
+	}
+}
+
+func TestTextChunkerMarkers(t *testing.T) {
+	got := chunks(t, []string{"pending", elevenlabs.FLUSH_MARKER, "more", elevenlabs.CLOSURE_MARKER})
+	want := []string{"pending", elevenlabs.FLUSH_MARKER, "more", elevenlabs.CLOSURE_MARKER}
+	if !slices.Equal(got, want) {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+}
+
+func TestTextChunkerCustomAbbreviations(t *testing.T) {
+	got := chunks(t, []string{"Ask approx. Nobody. Fine"}, elevenlabs.WithMinChunk(1), elevenlabs.WithAbbreviations("Nobody."))
+	want := []string{"Ask approx. ", "Nobody. Fine"}
+	if !slices.Equal(got, want) {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+}
+
+func TestTextChunkerIdleFlush(t *testing.T) {
+	in := make(chan string)
+	defer close(in)
+	c := elevenlabs.NewTextChunker(context.Background(), in, elevenlabs.WithIdleFlush(20*time.Millisecond))
+
+	in <- "no boundary yet"
+	for _, want := range []string{"no boundary yet", elevenlabs.FLUSH_MARKER} {
+		select {
+		case got := <-c.Output():
+			if got != want {
+				t.Fatalf("chunk = %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no %q after the idle timeout", want)
+		}
+	}
+}
+
+func TestTextChunkerCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan string)
+	c := elevenlabs.NewTextChunker(ctx, in)
+	cancel()
+
+	select {
+	case _, ok := <-c.Output():
+		if ok {
+			t.Error("chunk emitted after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("output not closed after cancel")
+	}
+}
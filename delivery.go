@@ -0,0 +1,163 @@
+package elevenlabs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// What the reader does when the alignment channel is not being drained
+type AlignmentDelivery int
+
+const (
+	DeliverBlock      AlignmentDelivery = iota // Wait for the consumer, stalling the socket read loop
+	DeliverDropOldest                          // Discard the oldest queued event to make room
+	DeliverDropNewest                          // Discard the event that does not fit
+	DeliverCoalesce                            // Merge events until the consumer catches up
+)
+
+func WithAlignmentDelivery(policy AlignmentDelivery) Option {
+	return func(o *options) {
+		o.delivery = policy
+	}
+}
+
+// Alignment channel wrapper applying the delivery policy, a nil channel discards everything
+type alignmentQueue[T any] struct {
+	ch      chan T
+	policy  AlignmentDelivery
+	merge   func(older T, newer T) T
+	dropped *atomic.Uint64
+
+	// DeliverCoalesce, guarded by mu since discard may run on another goroutine than push
+	mu        sync.Mutex
+	pending   *T   // Events not yet handed over
+	discarded bool // Later events are dropped
+}
+
+func newAlignmentQueue[T any](ch chan T, policy AlignmentDelivery, merge func(T, T) T, dropped *atomic.Uint64) *alignmentQueue[T] {
+	return &alignmentQueue[T]{ch: ch, policy: policy, merge: merge, dropped: dropped}
+}
+
+// Hand an event over, false once stop or ctx ended a blocking send. Final events are never coalesced away.
+func (q *alignmentQueue[T]) push(ctx context.Context, stop <-chan struct{}, v T, final bool) bool {
+	if q.ch == nil {
+		return true
+	}
+
+	switch q.policy {
+	case DeliverDropNewest:
+		select {
+		case q.ch <- v:
+		default:
+			q.dropped.Add(1)
+		}
+		return true
+
+	case DeliverDropOldest:
+		for {
+			select {
+			case q.ch <- v:
+				return true
+			default:
+			}
+			select {
+			case <-q.ch:
+				q.dropped.Add(1)
+			default:
+				// Unbuffered channel with no reader waiting, nothing older to evict
+				q.dropped.Add(1)
+				return true
+			}
+		}
+
+	case DeliverCoalesce:
+		q.mu.Lock()
+		if q.discarded {
+			q.mu.Unlock()
+			q.dropped.Add(1)
+			return true
+		}
+		if q.pending != nil {
+			v = q.merge(*q.pending, v)
+			q.pending = nil
+		}
+		if !final {
+			select {
+			case q.ch <- v:
+			default:
+				q.pending = &v
+			}
+			q.mu.Unlock()
+			return true
+		}
+		q.mu.Unlock() // Blocking send below
+	}
+
+	select {
+	case q.ch <- v:
+		return true
+	case <-stop:
+	case <-ctx.Done():
+	}
+	q.dropped.Add(1)
+	return false
+}
+
+// Count coalesced events that were never handed over, events pushed afterwards are dropped too
+func (q *alignmentQueue[T]) discard() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.discarded = true
+	if q.pending != nil {
+		q.pending = nil
+		q.dropped.Add(1)
+	}
+}
+
+// Alignment channel fed outside a streaming request, e.g. when replaying a recording, with the client's delivery policy
+type AlignmentForwarder struct {
+	ctx   context.Context
+	queue *alignmentQueue[StreamingOutputResponse]
+}
+
+// Forwarder to ch counting its drops in DroppedAlignments, a nil channel discards everything
+func (c *Client) ForwardAlignment(ch chan StreamingOutputResponse) *AlignmentForwarder {
+	return &AlignmentForwarder{ctx: c.ctx, queue: newAlignmentQueue(ch, c.opts.delivery, mergeStreamingOutput, c.dropped)}
+}
+
+// False once ctx or the client context ended a blocking send
+func (f *AlignmentForwarder) Send(ctx context.Context, resp StreamingOutputResponse) bool {
+	return f.queue.push(f.ctx, ctx.Done(), resp, resp.IsFinal)
+}
+
+// Count coalesced events that were never handed over
+func (f *AlignmentForwarder) Close() {
+	f.queue.discard()
+}
+
+// Join two consecutive segments, shifting the newer one past the end of the older chunk
+func mergeSegments(older StreamingAlignmentSegment, newer StreamingAlignmentSegment, olderMs int) StreamingAlignmentSegment {
+	return appendSegment(cloneSegment(older), newer, olderMs)
+}
+
+func mergeStreamingOutput(older StreamingOutputResponse, newer StreamingOutputResponse) StreamingOutputResponse {
+	olderMs := chunkEnd(older.Alignment, older.AudioDurationMs)
+	return StreamingOutputResponse{
+		IsFinal:             older.IsFinal || newer.IsFinal,
+		NormalizedAlignment: mergeSegments(older.NormalizedAlignment, newer.NormalizedAlignment, olderMs),
+		Alignment:           mergeSegments(older.Alignment, newer.Alignment, olderMs),
+		AudioDurationMs:     olderMs + chunkEnd(newer.Alignment, newer.AudioDurationMs),
+	}
+}
+
+func mergeMultiCtxOutput(older StreamingOutputMultiCtxResponse, newer StreamingOutputMultiCtxResponse) StreamingOutputMultiCtxResponse {
+	olderMs := chunkEnd(older.Alignment, older.AudioDurationMs)
+	return StreamingOutputMultiCtxResponse{
+		IsFinal:             older.IsFinal || newer.IsFinal,
+		NormalizedAlignment: mergeSegments(older.NormalizedAlignment, newer.NormalizedAlignment, olderMs),
+		Alignment:           mergeSegments(older.Alignment, newer.Alignment, olderMs),
+		AudioDurationMs:     olderMs + chunkEnd(newer.Alignment, newer.AudioDurationMs),
+		ContextId:           newer.ContextId,
+	}
+}
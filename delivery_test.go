@@ -0,0 +1,127 @@
+package elevenlabs
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func sum(older int, newer int) int { return older + newer }
+
+// Push events without a consumer, then return what the channel holds
+func pushAll(q *alignmentQueue[int], events ...int) []int {
+	for _, v := range events {
+		q.push(context.Background(), nil, v, false)
+	}
+	var got []int
+	for len(q.ch) > 0 {
+		got = append(got, <-q.ch)
+	}
+	return got
+}
+
+func TestAlignmentQueueDrops(t *testing.T) {
+	tests := []struct {
+		policy  AlignmentDelivery
+		want    []int
+		dropped uint64
+	}{
+		{DeliverDropNewest, []int{1, 2}, 2},
+		{DeliverDropOldest, []int{3, 4}, 2},
+		{DeliverCoalesce, []int{1, 2}, 0}, // 3+4 still pending
+	}
+	for _, tt := range tests {
+		var dropped atomic.Uint64
+		q := newAlignmentQueue(make(chan int, 2), tt.policy, sum, &dropped)
+		if got := pushAll(q, 1, 2, 3, 4); !slices.Equal(got, tt.want) || dropped.Load() != tt.dropped {
+			t.Errorf("policy %d delivered %v and dropped %d, want %v and %d", tt.policy, got, dropped.Load(), tt.want, tt.dropped)
+		}
+	}
+
+	// Unbuffered and nobody receiving, there is nothing older to evict
+	var dropped atomic.Uint64
+	q := newAlignmentQueue(make(chan int), DeliverDropOldest, sum, &dropped)
+	if !q.push(context.Background(), nil, 1, false) || dropped.Load() != 1 {
+		t.Errorf("unbuffered DropOldest dropped %d", dropped.Load())
+	}
+}
+
+func TestAlignmentQueueCoalesce(t *testing.T) {
+	var dropped atomic.Uint64
+	q := newAlignmentQueue(make(chan int, 1), DeliverCoalesce, sum, &dropped)
+	if got := pushAll(q, 1, 2, 3); !slices.Equal(got, []int{1}) {
+		t.Fatalf("delivered %v", got)
+	}
+
+	// The final event carries everything merged since, with a blocking send
+	done := make(chan bool)
+	go func() {
+		done <- q.push(context.Background(), nil, 4, true)
+	}()
+	if v := <-q.ch; v != 2+3+4 {
+		t.Errorf("final event = %d, want %d", v, 2+3+4)
+	}
+	if !<-done || dropped.Load() != 0 {
+		t.Errorf("dropped %d", dropped.Load())
+	}
+
+	pushAll(q, 5, 6)
+	q.discard()
+	if dropped.Load() != 1 {
+		t.Errorf("discarded pending events counted %d times", dropped.Load())
+	}
+
+	// Events arriving after the discard are dropped rather than left pending
+	if got := pushAll(q, 7); len(got) != 0 || dropped.Load() != 2 {
+		t.Errorf("after discard delivered %v, dropped %d", got, dropped.Load())
+	}
+}
+
+func TestAlignmentQueueBlock(t *testing.T) {
+	var dropped atomic.Uint64
+	q := newAlignmentQueue(make(chan int), DeliverBlock, sum, &dropped)
+	stop := make(chan struct{})
+	close(stop)
+	if q.push(context.Background(), stop, 1, false) || dropped.Load() != 1 {
+		t.Errorf("blocked push after stop, dropped %d", dropped.Load())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if q.push(ctx, nil, 1, false) {
+		t.Error("blocked push after cancel")
+	}
+
+	if !newAlignmentQueue[int](nil, DeliverBlock, sum, &dropped).push(context.Background(), nil, 1, true) {
+		t.Error("nil channel does not discard")
+	}
+}
+
+func TestMergeSegments(t *testing.T) {
+	older := StreamingAlignmentSegment{Chars: []string{"a", "b"}, CharStartTimesMs: []int{0, 10}, CharDurationsMs: []int{10, 10}}
+	newer := StreamingAlignmentSegment{Chars: []string{"c"}, CharStartTimesMs: []int{0}, CharDurationsMs: []int{5}}
+	merged := mergeSegments(older, newer, 20)
+
+	if !slices.Equal(merged.Chars, []string{"a", "b", "c"}) || !slices.Equal(merged.CharStartTimesMs, []int{0, 10, 20}) {
+		t.Errorf("merged = %+v", merged)
+	}
+	if len(older.Chars) != 2 {
+		t.Errorf("older segment modified: %+v", older)
+	}
+}
+
+func TestMergeOutputKeepsTrailingSilence(t *testing.T) {
+	older := StreamingOutputResponse{
+		Alignment:       StreamingAlignmentSegment{Chars: []string{"a"}, CharStartTimesMs: []int{0}, CharDurationsMs: []int{10}},
+		AudioDurationMs: 50,
+	}
+	newer := StreamingOutputResponse{
+		Alignment:       StreamingAlignmentSegment{Chars: []string{"b"}, CharStartTimesMs: []int{5}, CharDurationsMs: []int{10}},
+		AudioDurationMs: 30,
+	}
+	merged := mergeStreamingOutput(mergeStreamingOutput(older, newer), newer)
+	if !slices.Equal(merged.Alignment.CharStartTimesMs, []int{0, 55, 85}) || merged.AudioDurationMs != 110 {
+		t.Errorf("merged = %+v", merged)
+	}
+}
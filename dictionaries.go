@@ -0,0 +1,197 @@
+package elevenlabs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+)
+
+// Locators accepted by the API per request
+const MAX_PRONUNCIATION_DICTIONARIES = 3
+
+type PronunciationRuleType string
+
+const (
+	RuleAlias   PronunciationRuleType = "alias"
+	RulePhoneme PronunciationRuleType = "phoneme"
+)
+
+type PronunciationRule struct {
+	Type            PronunciationRuleType `json:"type"`
+	StringToReplace string                `json:"string_to_replace"`
+	Alias           string                `json:"alias,omitempty"`    // RuleAlias
+	Phoneme         string                `json:"phoneme,omitempty"`  // RulePhoneme
+	Alphabet        string                `json:"alphabet,omitempty"` // RulePhoneme, "ipa" or "cmu-arpabet"
+}
+
+func AliasRule(text string, alias string) PronunciationRule {
+	return PronunciationRule{Type: RuleAlias, StringToReplace: text, Alias: alias}
+}
+
+func PhonemeRule(text string, phoneme string, alphabet string) PronunciationRule {
+	return PronunciationRule{Type: RulePhoneme, StringToReplace: text, Phoneme: phoneme, Alphabet: alphabet}
+}
+
+type PronunciationDictionary struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	CreatedBy        string `json:"created_by"`
+	CreationTimeUnix int64  `json:"creation_time_unix"`
+	VersionID        string `json:"version_id"`        // Create responses
+	LatestVersionID  string `json:"latest_version_id"` // Get and list responses
+}
+
+// Locator of the dictionary's newest known version
+func (d PronunciationDictionary) Locator() PronunciationDictionaryLocators {
+	version := d.LatestVersionID
+	if version == "" {
+		version = d.VersionID
+	}
+	return PronunciationDictionaryLocators{DictionaryId: d.ID, VersionId: version}
+}
+
+type PronunciationDictionariesPage struct {
+	PronunciationDictionaries []PronunciationDictionary `json:"pronunciation_dictionaries"`
+	NextCursor                string                    `json:"next_cursor"`
+	HasMore                   bool                      `json:"has_more"`
+}
+
+// Dictionaries attached to every context opened by the client
+func WithPronunciationDictionaries(locators ...PronunciationDictionaryLocators) Option {
+	return func(o *options) {
+		o.dictionaries = locators
+	}
+}
+
+// Per-context initialization setting, e.g. for OpenContext
+type ContextFunc func(*TextToSpeechInputMultiStreamingRequest)
+
+// Dictionaries for one context, replacing those set with WithPronunciationDictionaries
+func PronunciationDictionaries(locators ...PronunciationDictionaryLocators) ContextFunc {
+	return func(r *TextToSpeechInputMultiStreamingRequest) {
+		r.PronunciationDictionaryLocators = locators
+	}
+}
+
+// Context initialization frame with the client dictionaries and ctxFuncs applied
+func (o *options) initFrame(frame TextToSpeechInputMultiStreamingRequest, ctxFuncs ...ContextFunc) (TextToSpeechInputMultiStreamingRequest, error) {
+	if frame.PronunciationDictionaryLocators == nil {
+		frame.PronunciationDictionaryLocators = o.dictionaries
+	}
+	for _, cf := range ctxFuncs {
+		cf(&frame)
+	}
+	if n := len(frame.PronunciationDictionaryLocators); n > MAX_PRONUNCIATION_DICTIONARIES {
+		return frame, fmt.Errorf("%d pronunciation dictionaries, at most %d allowed", n, MAX_PRONUNCIATION_DICTIONARIES)
+	}
+	return frame, nil
+}
+
+// Create a dictionary from a PLS lexicon file
+func (c *Client) AddPronunciationDictionaryFromFile(name string, description string, pls io.Reader) (*PronunciationDictionary, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", name)
+	if description != "" {
+		mw.WriteField("description", description)
+	}
+	fw, err := mw.CreateFormFile("file", name+".pls")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(fw, pls); err != nil {
+		return nil, fmt.Errorf("reading lexicon: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	req := newRestRequest(http.MethodPost, "/pronunciation-dictionaries/add-from-file")
+	req.body, req.contentType = body.Bytes(), mw.FormDataContentType()
+
+	var r PronunciationDictionary
+	if _, err := c.sendJSON(c.ctx, req, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client) AddPronunciationDictionaryFromRules(name string, description string, rules []PronunciationRule) (*PronunciationDictionary, error) {
+	in := struct {
+		Name        string              `json:"name"`
+		Description string              `json:"description,omitempty"`
+		Rules       []PronunciationRule `json:"rules"`
+	}{name, description, rules}
+
+	var r PronunciationDictionary
+	if err := c.doJSON(http.MethodPost, "/pronunciation-dictionaries/add-from-rules", in, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client) GetPronunciationDictionary(id string) (*PronunciationDictionary, error) {
+	var r PronunciationDictionary
+	if err := c.doJSON(http.MethodGet, "/pronunciation-dictionaries/"+neturl.PathEscape(id), nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// One page of dictionaries, pass the previous NextCursor to continue
+func (c *Client) ListPronunciationDictionaries(cursor string, pageSize int) (*PronunciationDictionariesPage, error) {
+	q := neturl.Values{}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	if pageSize > 0 {
+		q.Set("page_size", strconv.Itoa(pageSize))
+	}
+	path := "/pronunciation-dictionaries"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	var r PronunciationDictionariesPage
+	if err := c.doJSON(http.MethodGet, path, nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Add or replace rules, the locator points at the new version
+func (c *Client) AddPronunciationRules(id string, rules []PronunciationRule) (PronunciationDictionaryLocators, error) {
+	in := struct {
+		Rules []PronunciationRule `json:"rules"`
+	}{rules}
+	return c.updateRules(id, "add-rules", in)
+}
+
+// Remove the rules for the given strings, the locator points at the new version
+func (c *Client) RemovePronunciationRules(id string, ruleStrings []string) (PronunciationDictionaryLocators, error) {
+	in := struct {
+		RuleStrings []string `json:"rule_strings"`
+	}{ruleStrings}
+	return c.updateRules(id, "remove-rules", in)
+}
+
+func (c *Client) updateRules(id string, action string, in any) (PronunciationDictionaryLocators, error) {
+	var r struct {
+		ID        string `json:"id"`
+		VersionID string `json:"version_id"`
+	}
+	if err := c.doJSON(http.MethodPost, fmt.Sprintf("/pronunciation-dictionaries/%s/%s", neturl.PathEscape(id), action), in, &r); err != nil {
+		return PronunciationDictionaryLocators{}, err
+	}
+	return PronunciationDictionaryLocators{DictionaryId: r.ID, VersionId: r.VersionID}, nil
+}
+
+// Dictionaries set with WithPronunciationDictionaries
+func (c *Client) DefaultDictionaries() []PronunciationDictionaryLocators {
+	return c.opts.dictionaries
+}
@@ -0,0 +1,137 @@
+package elevenlabs_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+func TestPronunciationDictionaryEndpoints(t *testing.T) {
+	type call struct {
+		method, path, query, contentType, body string
+	}
+	var mu sync.Mutex
+	var calls []call
+	srv, _ := scripted(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		calls = append(calls, call{r.Method, r.URL.EscapedPath(), r.URL.RawQuery, r.Header.Get("Content-Type"), string(b)})
+		mu.Unlock()
+		io.WriteString(w, `{"id":"d1","version_id":"v2","latest_version_id":"v3","next_cursor":"next","has_more":true}`)
+	})
+	c := restClient(t, srv.URL)
+
+	d, err := c.AddPronunciationDictionaryFromRules("names", "", []elevenlabs.PronunciationRule{elevenlabs.AliasRule("UN", "United Nations")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc := d.Locator(); loc.DictionaryId != "d1" || loc.VersionId != "v3" {
+		t.Errorf("locator = %+v, want the latest version", loc)
+	}
+	if _, err := c.AddPronunciationDictionaryFromFile("names", "people", strings.NewReader("<lexicon/>")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetPronunciationDictionary("d/1"); err != nil {
+		t.Fatal(err)
+	}
+	page, err := c.ListPronunciationDictionaries("abc", 10)
+	if err != nil || page.NextCursor != "next" || !page.HasMore {
+		t.Fatalf("ListPronunciationDictionaries = %+v, %v", page, err)
+	}
+	loc, err := c.AddPronunciationRules("d1", []elevenlabs.PronunciationRule{elevenlabs.PhonemeRule("tomato", "təˈmɑːtoʊ", "ipa")})
+	if err != nil || loc.DictionaryId != "d1" || loc.VersionId != "v2" {
+		t.Fatalf("AddPronunciationRules = %+v, %v", loc, err)
+	}
+	if _, err := c.RemovePronunciationRules("d1", []string{"UN"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 6 {
+		t.Fatalf("%d calls", len(calls))
+	}
+	want := []call{
+		{method: "POST", path: "/pronunciation-dictionaries/add-from-rules", body: `{"name":"names","rules":[{"type":"alias","string_to_replace":"UN","alias":"United Nations"}]}`},
+		{method: "POST", path: "/pronunciation-dictionaries/add-from-file"},
+		{method: "GET", path: "/pronunciation-dictionaries/d%2F1"},
+		{method: "GET", path: "/pronunciation-dictionaries", query: "cursor=abc&page_size=10"},
+		{method: "POST", path: "/pronunciation-dictionaries/d1/add-rules", body: `{"rules":[{"type":"phoneme","string_to_replace":"tomato","phoneme":"təˈmɑːtoʊ","alphabet":"ipa"}]}`},
+		{method: "POST", path: "/pronunciation-dictionaries/d1/remove-rules", body: `{"rule_strings":["UN"]}`},
+	}
+	for i, w := range want {
+		got := calls[i]
+		if got.method != w.method || got.path != w.path || got.query != w.query {
+			t.Errorf("call %d = %s %s?%s, want %s %s?%s", i, got.method, got.path, got.query, w.method, w.path, w.query)
+		}
+		if w.body != "" && strings.TrimSpace(got.body) != w.body {
+			t.Errorf("call %d body = %s", i, got.body)
+		}
+	}
+
+	upload := calls[1]
+	if !strings.HasPrefix(upload.contentType, "multipart/form-data") {
+		t.Errorf("upload content type = %q", upload.contentType)
+	}
+	for _, part := range []string{`name="name"`, `name="description"`, `filename="names.pls"`, "<lexicon/>"} {
+		if !strings.Contains(upload.body, part) {
+			t.Errorf("upload body misses %s", part)
+		}
+	}
+}
+
+func TestPronunciationDictionariesPerContext(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	client := elevenlabs.PronunciationDictionaryLocators{DictionaryId: "client", VersionId: "v1"}
+	own := elevenlabs.PronunciationDictionaryLocators{DictionaryId: "own", VersionId: "v1"}
+	c := elevenlabs.NewMultiClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()),
+		elevenlabs.WithPronunciationDictionaries(client))
+	if err := c.Connect("voice", "model"); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	sink := elevenlabs.ContextSink{Audio: io.Discard}
+	if err := c.OpenContext("default", nil, sink); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.OpenContext("own", nil, sink, elevenlabs.PronunciationDictionaries(own)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.OpenContext("none", nil, sink, elevenlabs.PronunciationDictionaries()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.OpenContext("many", nil, sink, elevenlabs.PronunciationDictionaries(own, own, own, own)); err == nil {
+		t.Error("context opened with 4 dictionaries")
+	}
+	c.Close()
+
+	// The session's own initialization context is not ours to check
+	want := map[string][]string{"default": {"client"}, "own": {"own"}, "none": nil}
+	got := map[string][]string{}
+	for _, r := range srv.Requests() {
+		if _, ok := want[r.Frame.ContextID]; !ok || r.Frame.Text != " " {
+			continue
+		}
+		got[r.Frame.ContextID] = nil
+		for _, l := range r.Frame.PronunciationDictionaryLocators {
+			got[r.Frame.ContextID] = append(got[r.Frame.ContextID], l.DictionaryId)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("init frames for %v", got)
+	}
+	for id, ids := range want {
+		if !slices.Equal(got[id], ids) {
+			t.Errorf("context %q dictionaries = %v, want %v", id, got[id], ids)
+		}
+	}
+}
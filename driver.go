@@ -0,0 +1,421 @@
+package elevenlabs
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Returned when the server goes silent for the request timeout after the end of input, the audio may be incomplete
+var ErrDrainTimeout = errors.New("server did not close the socket after draining")
+
+// Streaming driver lifecycle, shared by StreamingRequest and MultiCtxStreamingRequest
+type driverState int32
+
+const (
+	driverConnecting driverState = iota // Dialing or redialing
+	driverStreaming                     // Forwarding input events to the socket
+	driverDraining                      // Input finished, waiting for the server to close
+	driverClosed                        // Finished cleanly
+	driverFailed                        // Finished with an error
+)
+
+func (s driverState) String() string {
+	switch s {
+	case driverConnecting:
+		return "connecting"
+	case driverStreaming:
+		return "streaming"
+	case driverDraining:
+		return "draining"
+	case driverClosed:
+		return "closed"
+	case driverFailed:
+		return "failed"
+	}
+	return fmt.Sprintf("driverState(%d)", int32(s))
+}
+
+type streamDriver struct {
+	ctx     context.Context
+	apiKey  string
+	timeout time.Duration
+	opts    *options
+	log     *slog.Logger
+	tenant  string // Charged for the text sent
+
+	url        string
+	queries    []QueryFunc
+	initFrames []TextToSpeechInputMultiStreamingRequest
+	contextID  string // Frames for other contexts are ignored
+
+	input   <-chan InputEvent
+	audio   io.Writer
+	deliver func(resp StreamingOutputResponse, stop <-chan struct{}) bool // False once stopped
+
+	state  atomic.Int32
+	format AudioFormatInfo // Output format, zero when the queries do not resolve
+	replay replayBuffer
+	eof    bool          // End of input sent, re-sent after a reconnect
+	marked bool          // No audio written since the last boundary, owned by the current reader
+	voice  chunkSettings // Voice settings of the context, changes go out with the next text chunk
+	conn   *websocket.Conn
+	reader *frameReader
+	start  time.Time
+}
+
+// Reader goroutine of a single connection
+type frameReader struct {
+	stop     chan struct{}   // Closed by the driver to abandon the connection
+	done     chan readResult // Exactly one result, buffered so the reader never blocks
+	activity chan struct{}   // Signalled on every frame received, buffered so the reader never blocks
+	exited   chan struct{}
+}
+
+type readResult struct {
+	err   error // Nil when the server closed the socket normally
+	retry bool  // Transport failure worth a reconnect
+}
+
+func (d *streamDriver) setState(s driverState) {
+	if prev := driverState(d.state.Swap(int32(s))); prev != s {
+		d.log.Debug("driver state", "from", prev.String(), "to", s.String())
+	}
+}
+
+func (d *streamDriver) getState() driverState {
+	return driverState(d.state.Load())
+}
+
+// Drive the request until the input is drained, the context is done or the connection fails
+func (d *streamDriver) run() error {
+	d.start = time.Now()
+	d.setState(driverConnecting)
+	format, err := ResolveAudioFormat(d.queries...)
+	announceFormat(d.audio, format, err)
+	if err == nil {
+		d.format = format
+	}
+
+	conn, err := d.open()
+	if err != nil {
+		d.setState(driverFailed)
+		d.log.Error("streaming connect failed", "error", err)
+		return err
+	}
+	d.attach(conn)
+	d.setState(driverStreaming)
+	defer d.detach()
+
+	input := d.input
+	var drain *time.Timer
+	var drainC <-chan time.Time
+	defer func() {
+		if drain != nil {
+			drain.Stop()
+		}
+	}()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return d.cancelled()
+
+		case <-drainC:
+			return d.fail(ErrDrainTimeout)
+
+		case <-d.reader.activity:
+			// Draining only times out while the server is silent
+			if drain != nil {
+				drain.Reset(d.timeout)
+			}
+
+		case res := <-d.reader.done:
+			if d.ctx.Err() != nil {
+				// The reader also stops when alignment delivery is cancelled
+				return d.cancelled()
+			}
+			if res.err == nil {
+				d.setState(driverClosed)
+				d.log.Info("streaming request finished", "duration", time.Since(d.start))
+				return nil
+			}
+			if res.retry && d.opts.reconnect != nil && d.ctx.Err() == nil {
+				if res.err = d.reconnect(res.err); res.err == nil {
+					continue
+				}
+			}
+			if d.ctx.Err() != nil {
+				return d.cancelled()
+			}
+			return d.fail(res.err)
+
+		case ev, ok := <-input:
+			frames, closing := d.frames(ev, ok)
+			if closing {
+				input = nil
+				d.setState(driverDraining)
+				if d.timeout > 0 {
+					drain = time.NewTimer(d.timeout)
+					drainC = drain.C
+				}
+			}
+
+			if err := d.write(frames...); err != nil {
+				if d.opts.reconnect != nil && reconnectable(err) && d.ctx.Err() == nil {
+					err = d.reconnect(err)
+				}
+				if d.ctx.Err() != nil {
+					return d.cancelled()
+				}
+				if err != nil {
+					return d.fail(err)
+				}
+			}
+		}
+	}
+}
+
+// Frames for an input event, closing once no more input is read
+func (d *streamDriver) frames(ev InputEvent, ok bool) (frames []any, closing bool) {
+	if !ok {
+		d.eof = true
+		return []any{map[string]string{"text": ""}}, true
+	}
+
+	var reqs []TextToSpeechInputMultiStreamingRequest
+	switch ev.Kind {
+	case InputText:
+		d.log.Debug("sending chunk", d.opts.textAttr(ev.Text))
+		req := TextToSpeechInputMultiStreamingRequest{Text: ev.Text, ContextID: d.contextID}
+		d.voice.apply(&req, ev.Overrides)
+		reqs = append(reqs, req)
+	case InputFlush:
+		d.log.Debug("sending flush")
+		reqs = append(reqs, TextToSpeechInputMultiStreamingRequest{Flush: true, ContextID: d.contextID})
+	case InputCloseContext:
+		// The driver owns a single context, the socket goes with it
+		d.log.Debug("sending context closure")
+		reqs = append(reqs,
+			TextToSpeechInputMultiStreamingRequest{CloseContext: true, ContextID: d.contextID},
+			TextToSpeechInputMultiStreamingRequest{CloseSocket: true},
+		)
+		closing = true
+	case InputCloseSocket:
+		d.log.Debug("sending socket closure")
+		reqs = append(reqs, TextToSpeechInputMultiStreamingRequest{CloseSocket: true})
+		closing = true
+	case InputUpdateVoiceSettings:
+		d.voice.setVoice(ev.VoiceSettings)
+	default:
+		d.log.Warn("ignoring unknown input event", "kind", ev.Kind)
+	}
+
+	for _, req := range reqs {
+		d.replay.add(req)
+		frames = append(frames, req)
+	}
+	return frames, closing
+}
+
+func (d *streamDriver) fail(err error) error {
+	d.setState(driverFailed)
+	d.log.Error("streaming request failed", "error", err, "duration", time.Since(d.start))
+	return err
+}
+
+// Cancellation ends the request with ctx.Err() wherever it interrupts it, the audio is incomplete
+func (d *streamDriver) cancelled() error {
+	d.setState(driverFailed)
+	d.log.Info("streaming request cancelled", "duration", time.Since(d.start))
+	return d.ctx.Err()
+}
+
+func (d *streamDriver) write(frames ...any) error {
+	for _, frame := range frames {
+		if d.timeout > 0 {
+			d.conn.SetWriteDeadline(time.Now().Add(d.timeout))
+		}
+		if err := d.conn.WriteJSON(frame); err != nil {
+			return err
+		}
+		if req, ok := frame.(TextToSpeechInputMultiStreamingRequest); ok {
+			d.opts.quota.Add(d.tenant, billedChars(req.Text))
+		}
+	}
+	return nil
+}
+
+// Dial a streaming socket and send its initialization frames
+func (d *streamDriver) open() (*websocket.Conn, error) {
+	start := time.Now()
+	conn, err := dialStream(d.ctx, d.opts.wsDialer(d.timeout), d.apiKey, d.url, d.queries...)
+	if err != nil {
+		return nil, err
+	}
+
+	d.log.Info("connected to Eleven Labs TTS websocket", "latency", time.Since(start))
+	for _, frame := range d.initFrames {
+		d.log.Debug("sending initialization request", "voice_settings", frame.VoiceSettings != nil)
+		if err := conn.WriteJSON(frame); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// Start reading from conn
+func (d *streamDriver) attach(conn *websocket.Conn) {
+	d.conn = conn
+	d.reader = &frameReader{
+		stop:     make(chan struct{}),
+		done:     make(chan readResult, 1),
+		activity: make(chan struct{}, 1),
+		exited:   make(chan struct{}),
+	}
+	go d.read(conn, d.reader)
+}
+
+// Close the current connection and wait for its reader to exit
+func (d *streamDriver) detach() {
+	if d.reader == nil {
+		return
+	}
+	close(d.reader.stop)
+	d.conn.Close()
+	<-d.reader.exited
+	d.reader = nil
+}
+
+func (d *streamDriver) read(conn *websocket.Conn, r *frameReader) {
+	defer close(r.exited)
+	r.done <- d.readFrames(conn, r)
+}
+
+// Response watching
+func (d *streamDriver) readFrames(conn *websocket.Conn, r *frameReader) readResult {
+	var firstAudio bool
+	for {
+		var frame StreamingOutputMultiCtxRawResponse
+		if err := conn.ReadJSON(&frame); err != nil {
+			select {
+			case <-r.stop:
+				return readResult{} // Abandoned by the driver
+			default:
+			}
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return readResult{}
+			}
+			err = classifyError(err)
+			return readResult{err: err, retry: reconnectable(err)}
+		}
+		select {
+		case r.activity <- struct{}{}:
+		default:
+		}
+		if id := frame.contextID(); id != "" && id != d.contextID {
+			continue // Initialization context, its errors included
+		}
+		if err := frameError(frame.Error, frame.Message); err != nil {
+			return readResult{err: err, retry: reconnectable(err)}
+		}
+		flushed := d.replay.ack(frame.IsFinal, len(frame.Alignment.Chars), frame.Audio != "")
+
+		b, err := base64.StdEncoding.DecodeString(frame.Audio)
+		if err != nil {
+			return readResult{err: fmt.Errorf("decoding audio: %w", err)}
+		}
+		if len(b) > 0 {
+			if !firstAudio {
+				firstAudio = true
+				d.log.Info("first audio received", "latency", time.Since(d.start))
+			}
+			d.log.Debug("received audio", "bytes", len(b), "is_final", frame.IsFinal)
+
+			// Send audio through the pipeline
+			if _, err := d.audio.Write(b); err != nil {
+				return readResult{err: fmt.Errorf("writing audio: %w", err)}
+			}
+			d.marked = false
+		}
+		if (frame.IsFinal || flushed) && !d.marked {
+			d.marked = true
+			markBoundary(d.audio)
+		}
+
+		response := StreamingOutputResponse{
+			IsFinal:             frame.IsFinal,
+			NormalizedAlignment: frame.NormalizedAlignment,
+			Alignment:           frame.Alignment,
+			AudioDurationMs:     int(d.format.Duration(len(b)).Milliseconds()),
+		}
+		passAlignment(d.audio, response)
+
+		// Send non-audio via the response channel
+		if !d.deliver(response, r.stop) {
+			return readResult{}
+		}
+	}
+}
+
+// Redial with exponential backoff, re-send the init frames and replay unacknowledged frames
+func (d *streamDriver) reconnect(cause error) error {
+	resume := d.getState()
+	d.detach()
+	d.setState(driverConnecting)
+	d.log.Warn("streaming connection lost, reconnecting", "error", cause)
+
+	policy := d.opts.reconnect
+	backoff := policy.InitialBackoff
+
+	var err error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		select {
+		case <-time.After(backoff):
+		case <-d.ctx.Done():
+			return d.ctx.Err()
+		}
+
+		event := ReconnectEvent{Attempt: attempt, Cause: cause, Backoff: backoff}
+		var conn *websocket.Conn
+		conn, err = d.open()
+		if err == nil {
+			frames := d.replay.pending()
+			for _, frame := range frames {
+				if err = conn.WriteJSON(frame); err != nil {
+					break
+				}
+				// Replayed text is generated, and billed, again
+				d.opts.quota.Add(d.tenant, billedChars(frame.Text))
+			}
+			if err == nil && d.eof {
+				err = conn.WriteJSON(map[string]string{"text": ""})
+			}
+			if err != nil {
+				conn.Close()
+			}
+			event.Replayed = len(frames)
+		}
+		event.Err = err
+		d.log.Info("reconnect attempt", "attempt", attempt, "replayed", event.Replayed, "error", err)
+		if policy.OnReconnect != nil {
+			policy.OnReconnect(event)
+		}
+		if err == nil {
+			d.attach(conn)
+			d.setState(resume)
+			return nil
+		}
+
+		backoff = min(backoff*2, policy.MaxBackoff)
+	}
+
+	return err
+}
@@ -0,0 +1,399 @@
+package elevenlabs_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/clearlyip/elevenlabs-go-websockets/elevenlabstest"
+)
+
+// AudioResponsePipe safe to read while the reader goroutine writes
+type audioBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (a *audioBuffer) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.buf.Write(p)
+}
+
+func (a *audioBuffer) String() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.buf.String()
+}
+
+// Fail the test if it leaves goroutines behind, registered before anything it has to wait for
+func checkGoroutines(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(2 * time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				buf := make([]byte, 1<<16)
+				t.Errorf("%d goroutines before, %d after\n%s", before, runtime.NumGoroutine(), buf[:runtime.Stack(buf, true)])
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+func newServer(t *testing.T, opts ...elevenlabstest.ServerOption) *elevenlabstest.Server {
+	srv := elevenlabstest.NewServer(opts...)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// Run StreamingRequest in the background, the result is sent once on the returned channel
+func stream(c *elevenlabs.Client, text chan string, alignment chan elevenlabs.StreamingOutputResponse, audio *audioBuffer) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- c.StreamingRequest(text, alignment, audio, "voice", "model", elevenlabs.TextToSpeechInputStreamingRequest{})
+	}()
+	return done
+}
+
+func wait(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("streaming request did not return")
+		return nil
+	}
+}
+
+func TestStreamingRequestClosureMarker(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+
+	text := make(chan string)
+	alignment := make(chan elevenlabs.StreamingOutputResponse, 16)
+	var audio audioBuffer
+	done := stream(c, text, alignment, &audio)
+	text <- "hello "
+	text <- "world"
+	text <- elevenlabs.CLOSURE_MARKER
+
+	if err := wait(t, done); err != nil {
+		t.Fatalf("StreamingRequest = %v", err)
+	}
+	if got := audio.String(); got != "hello world" {
+		t.Errorf("audio = %q", got)
+	}
+	var final bool
+	for len(alignment) > 0 {
+		final = (<-alignment).IsFinal
+	}
+	if !final {
+		t.Error("last alignment event is not final")
+	}
+}
+
+func TestStreamingRequestClosedTextReader(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+
+	text := make(chan string, 1)
+	text <- "hello"
+	close(text)
+	var audio audioBuffer
+	if err := wait(t, stream(c, text, nil, &audio)); err != nil {
+		t.Fatalf("StreamingRequest = %v", err)
+	}
+	if got := audio.String(); got != "hello" {
+		t.Errorf("audio = %q", got)
+	}
+}
+
+func TestStreamingRequestCancelled(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := elevenlabs.NewClient(ctx, "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+
+	text := make(chan string)
+	var audio audioBuffer
+	done := stream(c, text, nil, &audio)
+	text <- "hello"
+	cancel()
+
+	if err := wait(t, done); !errors.Is(err, context.Canceled) {
+		t.Errorf("StreamingRequest = %v, want context.Canceled", err)
+	}
+}
+
+func TestStreamingRequestDrainTimeout(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t, elevenlabstest.WithFaults(elevenlabstest.Delay(0, time.Second)))
+	c := elevenlabs.NewClient(context.Background(), "key", 100*time.Millisecond, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+
+	text := make(chan string)
+	close(text)
+	var audio audioBuffer
+	if err := wait(t, stream(c, text, nil, &audio)); !errors.Is(err, elevenlabs.ErrDrainTimeout) {
+		t.Errorf("StreamingRequest = %v, want ErrDrainTimeout", err)
+	}
+}
+
+func TestStreamingRequestDrainsSlowServer(t *testing.T) {
+	checkGoroutines(t)
+	// Six replies 100ms apart take longer than the timeout, but the server is never silent for that long
+	srv := newServer(t, elevenlabstest.WithFaults(elevenlabstest.Delay(0, 100*time.Millisecond)))
+	c := elevenlabs.NewClient(context.Background(), "key", 250*time.Millisecond, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+
+	text := make(chan string, 6)
+	for _, s := range []string{"a ", "b ", "c ", "d ", "e ", "f"} {
+		text <- s
+	}
+	close(text)
+	var audio audioBuffer
+	if err := wait(t, stream(c, text, nil, &audio)); err != nil {
+		t.Fatalf("StreamingRequest = %v", err)
+	}
+	if got := audio.String(); got != "a b c d e f" {
+		t.Errorf("audio = %q", got)
+	}
+}
+
+func TestStreamingRequestErrorFrame(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t, elevenlabstest.WithFaults(elevenlabstest.ErrorFrame(0, 1, "quota_exceeded", "no characters left")))
+	// Error frames are server decisions, reconnecting must not hide them
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()),
+		elevenlabs.WithReconnect(elevenlabs.ReconnectPolicy{InitialBackoff: time.Millisecond}))
+
+	text := make(chan string, 2)
+	text <- "hello "
+	text <- "world"
+	var audio audioBuffer
+	err := wait(t, stream(c, text, nil, &audio))
+
+	var apiErr *elevenlabs.APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, elevenlabs.ErrQuotaExceeded) {
+		t.Fatalf("StreamingRequest = %v, want ErrQuotaExceeded", err)
+	}
+	if apiErr.Message != "no characters left" {
+		t.Errorf("message = %q", apiErr.Message)
+	}
+	if n := srv.Connections(); n != 1 {
+		t.Errorf("connections = %d, want 1", n)
+	}
+}
+
+// Errors for other contexts on the socket, e.g. the initialization context, belong to them
+func TestStreamingRequestIgnoresOtherContextErrors(t *testing.T) {
+	checkGoroutines(t)
+	payload, _ := json.Marshal(map[string]string{"error": "input_timeout_exceeded", "message": "idle", "contextId": "init"})
+	srv := newServer(t, elevenlabstest.WithFaults(elevenlabstest.Fault{Kind: elevenlabstest.FaultError, Conn: 0, After: 1, Once: true, Payload: payload}))
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+
+	text := make(chan string, 2)
+	text <- "hello "
+	text <- "world"
+	close(text)
+	var audio audioBuffer
+	if err := wait(t, stream(c, text, nil, &audio)); err != nil {
+		t.Fatalf("StreamingRequest = %v", err)
+	}
+	// The error frame takes the place of the reply for "world"
+	if got := audio.String(); got != "hello " {
+		t.Errorf("audio = %q", got)
+	}
+}
+
+func TestStreamingRequestDroppedConnection(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t, elevenlabstest.WithFaults(elevenlabstest.Drop(1, 1)))
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+
+	text := make(chan string, 2)
+	text <- "hello "
+	text <- "world"
+	var audio audioBuffer
+	if err := wait(t, stream(c, text, nil, &audio)); err == nil {
+		t.Fatal("StreamingRequest succeeded on a dropped connection")
+	}
+}
+
+func TestStreamingRequestReconnectReplays(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t, elevenlabstest.WithFaults(elevenlabstest.Drop(1, 1)))
+	var mu sync.Mutex
+	var events []elevenlabs.ReconnectEvent
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()),
+		elevenlabs.WithReconnect(elevenlabs.ReconnectPolicy{
+			InitialBackoff: time.Millisecond,
+			OnReconnect: func(e elevenlabs.ReconnectEvent) {
+				mu.Lock()
+				events = append(events, e)
+				mu.Unlock()
+			},
+		}))
+
+	text := make(chan string)
+	var audio audioBuffer
+	done := stream(c, text, nil, &audio)
+	text <- "hello "
+	text <- elevenlabs.FLUSH_MARKER
+	text <- "world "
+	text <- "again"
+	text <- elevenlabs.CLOSURE_MARKER
+
+	if err := wait(t, done); err != nil {
+		t.Fatalf("StreamingRequest = %v", err)
+	}
+	// Acknowledged text is not generated twice
+	if got := audio.String(); got != "hello world again" {
+		t.Errorf("audio = %q", got)
+	}
+	if n := srv.Connections(); n != 2 {
+		t.Errorf("connections = %d, want 2", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 1 || events[0].Err != nil || events[0].Cause == nil {
+		t.Errorf("reconnect events = %+v", events)
+	}
+}
+
+func TestStreamingRequestUndrainedAlignment(t *testing.T) {
+	// DeliverCoalesce still waits to hand over the final event
+	for _, policy := range []elevenlabs.AlignmentDelivery{elevenlabs.DeliverDropNewest, elevenlabs.DeliverDropOldest} {
+		t.Run("", func(t *testing.T) {
+			checkGoroutines(t)
+			srv := newServer(t)
+			c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()),
+				elevenlabs.WithAlignmentDelivery(policy))
+
+			text := make(chan string, 4)
+			for _, s := range []string{"one ", "two ", "three", elevenlabs.CLOSURE_MARKER} {
+				text <- s
+			}
+			alignment := make(chan elevenlabs.StreamingOutputResponse) // Nobody reads it
+			var audio audioBuffer
+			if err := wait(t, stream(c, text, alignment, &audio)); err != nil {
+				t.Fatalf("StreamingRequest = %v", err)
+			}
+			if got := audio.String(); got != "one two three" {
+				t.Errorf("audio = %q", got)
+			}
+			if c.DroppedAlignments() == 0 {
+				t.Error("no dropped alignment events counted")
+			}
+		})
+	}
+}
+
+func TestStreamingRequestBlockedAlignmentCancelled(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := elevenlabs.NewClient(ctx, "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+
+	text := make(chan string, 2)
+	text <- "one "
+	text <- "two"
+	alignment := make(chan elevenlabs.StreamingOutputResponse) // Nobody reads it
+	var audio audioBuffer
+	done := stream(c, text, alignment, &audio)
+
+	// DeliverBlock stalls the read loop until the request is cancelled
+	select {
+	case err := <-done:
+		t.Fatalf("StreamingRequest returned %v while alignment was blocked", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	cancel()
+	if err := wait(t, done); !errors.Is(err, context.Canceled) {
+		t.Errorf("StreamingRequest = %v, want context.Canceled", err)
+	}
+}
+
+func TestMultiCtxStreamingRequest(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := elevenlabs.NewMultiClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+
+	text := make(chan string, 3)
+	for _, s := range []string{"hello ", "world", elevenlabs.CLOSURE_MARKER} {
+		text <- s
+	}
+	alignment := make(chan elevenlabs.StreamingOutputMultiCtxResponse, 16)
+	var audio audioBuffer
+	if err := c.MultiCtxStreamingRequest(text, alignment, &audio, "voice", "model"); err != nil {
+		t.Fatalf("MultiCtxStreamingRequest = %v", err)
+	}
+	if got := audio.String(); got != "hello world" {
+		t.Errorf("audio = %q", got)
+	}
+
+	var contextID string
+	var final bool
+	for len(alignment) > 0 {
+		resp := <-alignment
+		if contextID == "" {
+			contextID = resp.ContextId
+		}
+		if resp.ContextId != contextID {
+			t.Errorf("alignment for context %q and %q", contextID, resp.ContextId)
+		}
+		final = resp.IsFinal
+	}
+	if contextID == "" || !final {
+		t.Errorf("alignment context %q, final %v", contextID, final)
+	}
+	if !c.HasCapacity() {
+		t.Error("request slot not released")
+	}
+}
+
+func TestStreamingRequestReconnectGivesUp(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t, elevenlabstest.WithFaults(
+		elevenlabstest.Drop(1, 1),
+		elevenlabstest.Reject(2, http.StatusServiceUnavailable, "busy"),
+		elevenlabstest.Reject(3, http.StatusServiceUnavailable, "busy"),
+	))
+	var attempts atomic.Int32
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()),
+		elevenlabs.WithReconnect(elevenlabs.ReconnectPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+			OnReconnect: func(e elevenlabs.ReconnectEvent) {
+				attempts.Add(1)
+				if e.Err == nil {
+					t.Errorf("attempt %d succeeded", e.Attempt)
+				}
+			},
+		}))
+
+	text := make(chan string, 2)
+	text <- "hello "
+	text <- "world"
+	var audio audioBuffer
+	if err := wait(t, stream(c, text, nil, &audio)); err == nil {
+		t.Fatal("StreamingRequest succeeded after every redial failed")
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Errorf("%d reconnect attempts, want 2", n)
+	}
+}
@@ -0,0 +1,79 @@
+package elevenlabstest
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type FaultKind int
+
+const (
+	FaultDrop      FaultKind = iota // Close the TCP connection without a close frame
+	FaultDelay                      // Sleep before replying
+	FaultMalformed                  // Reply with a payload that is not valid JSON
+	FaultError                      // Reply with an error frame
+	FaultReject                     // Refuse the websocket handshake
+)
+
+type Fault struct {
+	Kind    FaultKind
+	Conn    int           // 1-based connection the fault applies to, 0 for every connection
+	After   int           // Replies sent on the connection before the fault fires
+	Once    bool          // Fire a single time per connection instead of on every later reply
+	Delay   time.Duration // FaultDelay
+	Payload []byte        // FaultMalformed, FaultError and FaultReject body
+	Status  int           // FaultReject
+	Close   bool          // FaultError, end the connection after the frame
+}
+
+// Drop the connection after n replies
+func Drop(conn int, after int) Fault {
+	return Fault{Kind: FaultDrop, Conn: conn, After: after}
+}
+
+// Delay every reply on the connection
+func Delay(conn int, d time.Duration) Fault {
+	return Fault{Kind: FaultDelay, Conn: conn, Delay: d}
+}
+
+// Send invalid JSON as the reply following n replies
+func Malformed(conn int, after int) Fault {
+	return Fault{Kind: FaultMalformed, Conn: conn, After: after, Once: true, Payload: []byte(`{"audio":`)}
+}
+
+// Send an error frame as the reply following n replies and close the connection
+func ErrorFrame(conn int, after int, code string, message string) Fault {
+	payload, _ := json.Marshal(map[string]any{
+		"error":   code,
+		"message": message,
+		"code":    1008,
+	})
+	return Fault{Kind: FaultError, Conn: conn, After: after, Once: true, Payload: payload, Close: true}
+}
+
+// Refuse the handshake with the given HTTP status
+func Reject(conn int, status int, body string) Fault {
+	return Fault{Kind: FaultReject, Conn: conn, Status: status, Payload: []byte(body)}
+}
+
+func (f Fault) applies(conn int, sent int) bool {
+	if f.Conn != 0 && f.Conn != conn {
+		return false
+	}
+	if f.Once {
+		return sent == f.After
+	}
+	return sent >= f.After
+}
+
+// First fault of the given kind due on this connection
+func (c *conn) fault(kind FaultKind) *Fault {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	for _, f := range c.server.faults {
+		if f.Kind == kind && f.applies(c.n, c.sent) {
+			return &f
+		}
+	}
+	return nil
+}
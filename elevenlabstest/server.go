@@ -0,0 +1,288 @@
+// Package elevenlabstest provides an in-process fake of the Eleven Labs TTS websockets:
+//   - Streaming Websocket: /v1/text-to-speech/:voice_id/stream-input
+//   - Multi-Context Websocket: /v1/text-to-speech/:voice_id/multi-stream-input
+//
+// Every text frame is answered with scripted audio and a character alignment, close_context and
+// close_socket are answered with isFinal. Faults can be injected per connection.
+package elevenlabstest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/gorilla/websocket"
+)
+
+const STREAM_ENDPOINT = "stream-input"
+const MULTI_STREAM_ENDPOINT = "multi-stream-input"
+
+// Frame received by the fake
+type Request struct {
+	Conn     int // 1-based connection number
+	Endpoint string
+	VoiceID  string
+	Query    neturl.Values
+	Header   http.Header
+	Frame    elevenlabs.TextToSpeechInputMultiStreamingRequest
+}
+
+// Frame sent by the fake
+type Response struct {
+	Audio               string                                `json:"audio,omitempty"`
+	IsFinal             bool                                  `json:"isFinal,omitempty"`
+	NormalizedAlignment *elevenlabs.StreamingAlignmentSegment `json:"normalizedAlignment,omitempty"`
+	Alignment           *elevenlabs.StreamingAlignmentSegment `json:"alignment,omitempty"`
+	ContextID           string                                `json:"contextId,omitempty"`
+}
+
+type Server struct {
+	*httptest.Server
+
+	audio        func(text string) []byte
+	charDuration time.Duration
+
+	mu       sync.Mutex
+	conns    int
+	requests []Request
+	faults   []Fault
+}
+
+type ServerOption func(*Server)
+
+// Start a fake server, Close it when done
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{
+		audio:        func(text string) []byte { return []byte(text) },
+		charDuration: 50 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/text-to-speech/{voice_id}/"+STREAM_ENDPOINT, s.handler(STREAM_ENDPOINT))
+	mux.HandleFunc("/v1/text-to-speech/{voice_id}/"+MULTI_STREAM_ENDPOINT, s.handler(MULTI_STREAM_ENDPOINT))
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Audio bytes returned for each text frame, defaults to the text itself
+func WithAudio(fn func(text string) []byte) ServerOption {
+	return func(s *Server) {
+		s.audio = fn
+	}
+}
+
+// Duration reported for every character in alignments
+func WithCharDuration(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.charDuration = d
+	}
+}
+
+func WithFaults(faults ...Fault) ServerOption {
+	return func(s *Server) {
+		s.faults = append(s.faults, faults...)
+	}
+}
+
+// Equivalent of ELEVEN_BASEURL_WSS for this server
+func (s *Server) WebsocketURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + "/v1"
+}
+
+// Equivalent of ELEVEN_BASEURL_HTTPS for this server
+func (s *Server) BaseURL() string {
+	return s.URL + "/v1"
+}
+
+// Add faults while the server is running
+func (s *Server) Inject(faults ...Fault) {
+	s.mu.Lock()
+	s.faults = append(s.faults, faults...)
+	s.mu.Unlock()
+}
+
+// Frames received so far, in arrival order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Number of websocket connections accepted so far
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+func (s *Server) handler(endpoint string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.conns++
+		c := &conn{
+			server:   s,
+			n:        s.conns,
+			endpoint: endpoint,
+			voiceID:  r.PathValue("voice_id"),
+			query:    r.URL.Query(),
+			header:   r.Header.Clone(),
+			contexts: make(map[string]struct{}),
+		}
+		s.mu.Unlock()
+
+		if f := c.fault(FaultReject); f != nil {
+			http.Error(w, string(f.Payload), f.Status)
+			return
+		}
+
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		c.ws = ws
+		c.serve()
+	}
+}
+
+type conn struct {
+	server   *Server
+	ws       *websocket.Conn
+	n        int
+	endpoint string
+	voiceID  string
+	query    neturl.Values
+	header   http.Header
+	contexts map[string]struct{}
+	sent     int
+}
+
+func (c *conn) serve() {
+	for {
+		_, msg, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var frame elevenlabs.TextToSpeechInputMultiStreamingRequest
+		if err := json.Unmarshal(msg, &frame); err != nil {
+			return
+		}
+		c.server.mu.Lock()
+		c.server.requests = append(c.server.requests, Request{
+			Conn:     c.n,
+			Endpoint: c.endpoint,
+			VoiceID:  c.voiceID,
+			Query:    c.query,
+			Header:   c.header,
+			Frame:    frame,
+		})
+		c.server.mu.Unlock()
+
+		if !c.handle(frame) {
+			return
+		}
+	}
+}
+
+// Reply to a single frame, false once the connection should end
+func (c *conn) handle(frame elevenlabs.TextToSpeechInputMultiStreamingRequest) bool {
+	id := frame.ContextID
+
+	switch {
+	case frame.CloseSocket:
+		for ctx := range c.contexts {
+			if !c.send(Response{IsFinal: true, ContextID: c.replyID(ctx)}) {
+				return false
+			}
+		}
+		c.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		return false
+	case frame.CloseContext:
+		delete(c.contexts, id)
+		return c.send(Response{IsFinal: true, ContextID: c.replyID(id)})
+	case frame.Text == "" && !frame.Flush:
+		// End of input
+		if len(c.contexts) == 0 {
+			c.contexts[id] = struct{}{}
+		}
+		for ctx := range c.contexts {
+			if !c.send(Response{IsFinal: true, ContextID: c.replyID(ctx)}) {
+				return false
+			}
+		}
+		c.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		return false
+	}
+
+	c.contexts[id] = struct{}{}
+	if strings.TrimSpace(frame.Text) == "" {
+		return true // Initialization or flush
+	}
+
+	alignment := c.server.align(frame.Text)
+	return c.send(Response{
+		Audio:               base64.StdEncoding.EncodeToString(c.server.audio(frame.Text)),
+		Alignment:           alignment,
+		NormalizedAlignment: alignment,
+		ContextID:           c.replyID(id),
+	})
+}
+
+// Context ids are only echoed on the multi-context endpoint
+func (c *conn) replyID(id string) string {
+	if c.endpoint != MULTI_STREAM_ENDPOINT {
+		return ""
+	}
+	return id
+}
+
+func (s *Server) align(text string) *elevenlabs.StreamingAlignmentSegment {
+	ms := int(s.charDuration / time.Millisecond)
+	seg := &elevenlabs.StreamingAlignmentSegment{}
+	for i, r := range []rune(text) {
+		seg.Chars = append(seg.Chars, string(r))
+		seg.CharStartTimesMs = append(seg.CharStartTimesMs, i*ms)
+		seg.CharDurationsMs = append(seg.CharDurationsMs, ms)
+	}
+	return seg
+}
+
+// Write a reply, applying any fault due at this point. False once the connection is gone.
+func (c *conn) send(resp Response) bool {
+	if f := c.fault(FaultDelay); f != nil {
+		time.Sleep(f.Delay)
+	}
+	if f := c.fault(FaultDrop); f != nil {
+		c.ws.UnderlyingConn().Close()
+		return false
+	}
+	if f := c.fault(FaultMalformed); f != nil {
+		c.sent++
+		return c.ws.WriteMessage(websocket.TextMessage, f.Payload) == nil
+	}
+	if f := c.fault(FaultError); f != nil {
+		c.sent++
+		if c.ws.WriteMessage(websocket.TextMessage, f.Payload) != nil {
+			return false
+		}
+		return !f.Close
+	}
+
+	c.sent++
+	return c.ws.WriteJSON(resp) == nil
+}
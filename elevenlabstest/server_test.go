@@ -0,0 +1,206 @@
+package elevenlabstest_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/clearlyip/elevenlabs-go-websockets/elevenlabstest"
+	"github.com/gorilla/websocket"
+)
+
+func dial(t *testing.T, srv *elevenlabstest.Server, endpoint string) *websocket.Conn {
+	t.Helper()
+	ws, _, err := websocket.DefaultDialer.Dial(srv.WebsocketURL()+"/text-to-speech/voice/"+endpoint+"?model_id=m", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func sendText(t *testing.T, ws *websocket.Conn, text string, contextID string) {
+	t.Helper()
+	if err := ws.WriteJSON(elevenlabs.TextToSpeechInputMultiStreamingRequest{Text: text, ContextID: contextID}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func readResponse(t *testing.T, ws *websocket.Conn) elevenlabstest.Response {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var resp elevenlabstest.Response
+	if err := ws.ReadJSON(&resp); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return resp
+}
+
+func TestServerRepliesWithAudioAndAlignment(t *testing.T) {
+	srv := elevenlabstest.NewServer(elevenlabstest.WithCharDuration(10 * time.Millisecond))
+	defer srv.Close()
+	ws := dial(t, srv, elevenlabstest.STREAM_ENDPOINT)
+
+	sendText(t, ws, " ", "")
+	sendText(t, ws, "héllo", "")
+	resp := readResponse(t, ws)
+
+	audio, err := base64.StdEncoding.DecodeString(resp.Audio)
+	if err != nil || string(audio) != "héllo" {
+		t.Fatalf("audio = %q, %v", audio, err)
+	}
+	if resp.Alignment == nil || strings.Join(resp.Alignment.Chars, "") != "héllo" {
+		t.Fatalf("alignment = %+v", resp.Alignment)
+	}
+	if got := resp.Alignment.CharStartTimesMs[4]; got != 40 {
+		t.Errorf("last char start = %d, want 40", got)
+	}
+	if resp.ContextID != "" {
+		t.Errorf("context id %q echoed on the single stream endpoint", resp.ContextID)
+	}
+
+	sendText(t, ws, "", "")
+	if resp := readResponse(t, ws); !resp.IsFinal {
+		t.Errorf("end of input answered with %+v, want isFinal", resp)
+	}
+	if _, _, err := ws.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("read after end of input = %v, want a normal close", err)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 3 || reqs[1].Frame.Text != "héllo" || reqs[0].VoiceID != "voice" || reqs[0].Query.Get("model_id") != "m" {
+		t.Errorf("requests = %+v", reqs)
+	}
+}
+
+func TestServerMultiContext(t *testing.T) {
+	srv := elevenlabstest.NewServer(elevenlabstest.WithAudio(func(text string) []byte { return []byte("audio") }))
+	defer srv.Close()
+	ws := dial(t, srv, elevenlabstest.MULTI_STREAM_ENDPOINT)
+
+	sendText(t, ws, "a", "one")
+	if resp := readResponse(t, ws); resp.ContextID != "one" || resp.Audio != base64.StdEncoding.EncodeToString([]byte("audio")) {
+		t.Fatalf("reply = %+v", resp)
+	}
+	sendText(t, ws, "b", "two")
+	readResponse(t, ws)
+
+	if err := ws.WriteJSON(elevenlabs.TextToSpeechInputMultiStreamingRequest{CloseContext: true, ContextID: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if resp := readResponse(t, ws); !resp.IsFinal || resp.ContextID != "one" {
+		t.Fatalf("close_context answered with %+v", resp)
+	}
+
+	if err := ws.WriteJSON(elevenlabs.TextToSpeechInputMultiStreamingRequest{CloseSocket: true}); err != nil {
+		t.Fatal(err)
+	}
+	if resp := readResponse(t, ws); !resp.IsFinal || resp.ContextID != "two" {
+		t.Fatalf("close_socket answered with %+v", resp)
+	}
+}
+
+func TestFaultReject(t *testing.T) {
+	srv := elevenlabstest.NewServer(elevenlabstest.WithFaults(elevenlabstest.Reject(1, http.StatusTooManyRequests, "slow down")))
+	defer srv.Close()
+
+	_, resp, err := websocket.DefaultDialer.Dial(srv.WebsocketURL()+"/text-to-speech/voice/"+elevenlabstest.STREAM_ENDPOINT, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("first dial = %v, %v", resp, err)
+	}
+
+	// Only the first connection is rejected
+	dial(t, srv, elevenlabstest.STREAM_ENDPOINT)
+	if n := srv.Connections(); n != 2 {
+		t.Errorf("connections = %d, want 2", n)
+	}
+}
+
+func TestFaultDropAfter(t *testing.T) {
+	srv := elevenlabstest.NewServer(elevenlabstest.WithFaults(elevenlabstest.Drop(1, 2)))
+	defer srv.Close()
+	ws := dial(t, srv, elevenlabstest.STREAM_ENDPOINT)
+
+	for _, text := range []string{"one", "two"} {
+		sendText(t, ws, text, "")
+		readResponse(t, ws)
+	}
+	sendText(t, ws, "three", "")
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := ws.ReadMessage()
+	if err == nil || websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("read after drop = %v, want an abnormal closure", err)
+	}
+
+	// Faults bound to a connection leave the others alone
+	ws = dial(t, srv, elevenlabstest.STREAM_ENDPOINT)
+	for _, text := range []string{"one", "two", "three"} {
+		sendText(t, ws, text, "")
+		readResponse(t, ws)
+	}
+}
+
+func TestFaultMalformedOnce(t *testing.T) {
+	srv := elevenlabstest.NewServer(elevenlabstest.WithFaults(elevenlabstest.Malformed(0, 1)))
+	defer srv.Close()
+	ws := dial(t, srv, elevenlabstest.STREAM_ENDPOINT)
+
+	var replies []string
+	for _, text := range []string{"one", "two", "three"} {
+		sendText(t, ws, text, "")
+		ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		replies = append(replies, string(msg))
+	}
+
+	for i, msg := range replies {
+		if valid := json.Valid([]byte(msg)); valid == (i == 1) {
+			t.Errorf("reply %d = %q, only the second should be malformed", i, msg)
+		}
+	}
+}
+
+func TestFaultErrorFrame(t *testing.T) {
+	srv := elevenlabstest.NewServer(elevenlabstest.WithFaults(elevenlabstest.ErrorFrame(1, 0, "quota_exceeded", "out of characters")))
+	defer srv.Close()
+	ws := dial(t, srv, elevenlabstest.STREAM_ENDPOINT)
+
+	sendText(t, ws, "one", "")
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var frame struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := ws.ReadJSON(&frame); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if frame.Error != "quota_exceeded" || frame.Message != "out of characters" {
+		t.Errorf("error frame = %+v", frame)
+	}
+	if _, _, err := ws.ReadMessage(); err == nil {
+		t.Error("connection still open after the error frame")
+	}
+}
+
+func TestFaultDelay(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	srv := elevenlabstest.NewServer()
+	defer srv.Close()
+	ws := dial(t, srv, elevenlabstest.STREAM_ENDPOINT)
+
+	// Injected while the connection is open, applies from the next reply
+	srv.Inject(elevenlabstest.Delay(1, delay))
+	start := time.Now()
+	sendText(t, ws, "one", "")
+	readResponse(t, ws)
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("reply after %v, want at least %v", elapsed, delay)
+	}
+}
@@ -0,0 +1,117 @@
+package elevenlabs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// Error reported by the Eleven Labs API, either as a websocket frame, a close frame or a REST body
+type APIError struct {
+	Code      string // Canonical error code, e.g. "quota_exceeded"
+	Message   string
+	Status    int    // HTTP status or websocket close code, 0 for error frames
+	Retryable bool   // Worth retrying the same request later
+	RequestID string // REST request-id header, for support requests
+}
+
+func (e *APIError) Error() string {
+	switch {
+	case e.Message != "" && e.Code != "":
+		return fmt.Sprintf("elevenlabs: %s: %s", e.Code, e.Message)
+	case e.Message != "":
+		return "elevenlabs: " + e.Message
+	default:
+		return "elevenlabs: " + e.Code
+	}
+}
+
+// Matches sentinel APIErrors by code, e.g. errors.Is(err, ErrQuotaExceeded)
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	if !ok {
+		return false
+	}
+	return t.Code != "" && t.Code == e.Code
+}
+
+var (
+	ErrQuotaExceeded         = &APIError{Code: "quota_exceeded"}
+	ErrInvalidVoice          = &APIError{Code: "voice_not_found"}
+	ErrInactivityTimeout     = &APIError{Code: "input_timeout_exceeded"}
+	ErrUnusualActivity       = &APIError{Code: "detected_unusual_activity"}
+	ErrInvalidAPIKey         = &APIError{Code: "invalid_api_key"}
+	ErrTooManyConcurrent     = &APIError{Code: "too_many_concurrent_requests", Retryable: true}
+	ErrSystemBusy            = &APIError{Code: "system_busy", Retryable: true}
+	ErrMaxCharactersExceeded = &APIError{Code: "max_character_limit_exceeded"}
+	ErrContextLimitExceeded  = &APIError{Code: "max_active_conversations", Retryable: true}
+	ErrInvalidRequest        = &APIError{Code: "invalid_request"}
+	ErrInternalServerError   = &APIError{Code: "internal_server_error", Retryable: true}
+	apiErrorSentinels        = []*APIError{ErrQuotaExceeded, ErrInvalidVoice, ErrInactivityTimeout, ErrUnusualActivity, ErrInvalidAPIKey, ErrTooManyConcurrent, ErrSystemBusy, ErrMaxCharactersExceeded, ErrContextLimitExceeded, ErrInvalidRequest, ErrInternalServerError}
+	apiErrorAliases          = map[string]string{
+		"invalid_voice":             "voice_not_found",
+		"invalid_voice_id":          "voice_not_found",
+		"voice_does_not_exist":      "voice_not_found",
+		"inactivity_timeout":        "input_timeout_exceeded",
+		"input_timeout":             "input_timeout_exceeded",
+		"unusual_activity":          "detected_unusual_activity",
+		"needs_authorization":       "invalid_api_key",
+		"unauthorized":              "invalid_api_key",
+		"rate_limit_exceeded":       "too_many_concurrent_requests",
+		"concurrent_limit_exceeded": "too_many_concurrent_requests",
+		"character_limit_exceeded":  "max_character_limit_exceeded",
+	}
+)
+
+func canonicalCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if canonical, ok := apiErrorAliases[code]; ok {
+		return canonical
+	}
+	return code
+}
+
+// Build an APIError, normalizing the code and inheriting the retryable flag of its sentinel
+func newAPIError(code string, message string, status int) *APIError {
+	code = canonicalCode(code)
+	e := &APIError{Code: code, Message: message, Status: status}
+	for _, s := range apiErrorSentinels {
+		if s.Code == code {
+			e.Retryable = s.Retryable
+			return e
+		}
+	}
+	e.Retryable = status == 429 || (status >= 500 && status < 600)
+	return e
+}
+
+// Error carried by a server frame, nil for regular audio/alignment frames
+func frameError(errCode string, message string) error {
+	if errCode == "" {
+		return nil
+	}
+	return newAPIError(errCode, message, 0)
+}
+
+// Map close frames carrying an API error code, "quota_exceeded: ..." style reasons included
+func classifyError(err error) error {
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code == websocket.CloseNormalClosure {
+		return err
+	}
+
+	reason := closeErr.Text
+	code, message, _ := strings.Cut(reason, ":")
+	code = canonicalCode(code)
+	for _, s := range apiErrorSentinels {
+		if s.Code == code {
+			return newAPIError(code, strings.TrimSpace(message), closeErr.Code)
+		}
+	}
+	if closeErr.Code == websocket.ClosePolicyViolation && reason != "" {
+		return newAPIError("", reason, closeErr.Code)
+	}
+	return err
+}
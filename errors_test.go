@@ -0,0 +1,75 @@
+package elevenlabs
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestFrameError(t *testing.T) {
+	if err := frameError("", ""); err != nil {
+		t.Fatalf("frameError without a code = %v", err)
+	}
+
+	err := frameError(" Invalid_Voice_ID ", "no such voice")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrInvalidVoice) {
+		t.Fatalf("frameError = %v, want ErrInvalidVoice", err)
+	}
+	if apiErr.Code != "voice_not_found" || apiErr.Message != "no such voice" || apiErr.Retryable {
+		t.Errorf("error = %+v", apiErr)
+	}
+	if got := err.Error(); got != "elevenlabs: voice_not_found: no such voice" {
+		t.Errorf("Error() = %q", got)
+	}
+	if errors.Is(err, ErrQuotaExceeded) || errors.Is(err, &APIError{}) {
+		t.Error("error matches other codes")
+	}
+
+	// Retryable flags come from the sentinel
+	if err := frameError("rate_limit_exceeded", ""); !errors.Is(err, ErrTooManyConcurrent) || !reconnectable(err) {
+		t.Errorf("rate limit = %v, want a retryable ErrTooManyConcurrent", err)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      *APIError // Nil when the error is returned as is
+		status    int
+		retryable bool
+	}{
+		{"coded reason", &websocket.CloseError{Code: websocket.ClosePolicyViolation, Text: "quota_exceeded: out of characters"},
+			ErrQuotaExceeded, websocket.ClosePolicyViolation, false},
+		{"alias", &websocket.CloseError{Code: websocket.ClosePolicyViolation, Text: "inactivity_timeout"},
+			ErrInactivityTimeout, websocket.ClosePolicyViolation, false},
+		{"retryable", &websocket.CloseError{Code: websocket.CloseTryAgainLater, Text: "system_busy: try later"},
+			ErrSystemBusy, websocket.CloseTryAgainLater, true},
+		{"policy reason", &websocket.CloseError{Code: websocket.ClosePolicyViolation, Text: "something else"},
+			&APIError{}, websocket.ClosePolicyViolation, false},
+		{"normal closure", &websocket.CloseError{Code: websocket.CloseNormalClosure, Text: "quota_exceeded"}, nil, 0, false},
+		{"uncoded close", &websocket.CloseError{Code: websocket.CloseGoingAway, Text: "bye"}, nil, 0, false},
+		{"transport", io.ErrUnexpectedEOF, nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(tt.err)
+			if tt.want == nil {
+				if err != tt.err {
+					t.Errorf("classifyError = %v, want it unchanged", err)
+				}
+				return
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("classifyError = %v, want an *APIError", err)
+			}
+			if apiErr.Code != tt.want.Code || apiErr.Status != tt.status || apiErr.Retryable != tt.retryable {
+				t.Errorf("error = %+v", apiErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package elevenlabs
+
+import (
+	"fmt"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value of the output_format query parameter
+type AudioFormat string
+
+const (
+	FormatMP3_22050_32  AudioFormat = "mp3_22050_32"
+	FormatMP3_44100_32  AudioFormat = "mp3_44100_32"
+	FormatMP3_44100_64  AudioFormat = "mp3_44100_64"
+	FormatMP3_44100_96  AudioFormat = "mp3_44100_96"
+	FormatMP3_44100_128 AudioFormat = "mp3_44100_128"
+	FormatMP3_44100_192 AudioFormat = "mp3_44100_192"
+	FormatPCM_8000      AudioFormat = "pcm_8000"
+	FormatPCM_16000     AudioFormat = "pcm_16000"
+	FormatPCM_22050     AudioFormat = "pcm_22050"
+	FormatPCM_24000     AudioFormat = "pcm_24000"
+	FormatPCM_44100     AudioFormat = "pcm_44100"
+	FormatPCM_48000     AudioFormat = "pcm_48000"
+	FormatULAW_8000     AudioFormat = "ulaw_8000"
+	FormatALAW_8000     AudioFormat = "alaw_8000"
+	FormatOpus_48000_32 AudioFormat = "opus_48000_32"
+	FormatOpus_48000_64 AudioFormat = "opus_48000_64"
+)
+
+// Format used by the API when output_format is not set
+const DEFAULT_OUTPUT_FORMAT = FormatMP3_44100_128
+
+type AudioEncoding string
+
+const (
+	EncodingMP3  AudioEncoding = "mp3"
+	EncodingPCM  AudioEncoding = "pcm_s16le" // Signed 16-bit little endian
+	EncodingULAW AudioEncoding = "ulaw"      // G.711 μ-law
+	EncodingALAW AudioEncoding = "alaw"      // G.711 A-law
+	EncodingOpus AudioEncoding = "opus"
+)
+
+// Stream properties of an AudioFormat
+type AudioFormatInfo struct {
+	Format        AudioFormat
+	Encoding      AudioEncoding
+	SampleRate    int // Hz
+	Channels      int
+	BitsPerSample int // 0 for compressed encodings
+	Bitrate       int // kbps, compressed encodings only
+}
+
+// AudioResponsePipe writers implementing this are told the stream format before the first audio write
+type AudioFormatReceiver interface {
+	SetAudioFormat(AudioFormatInfo)
+}
+
+// AudioResponsePipe writers implementing this are told when a generation ends (isFinal) or the audio
+// of a flush has been written, e.g. to start a new talkspurt
+type AudioBoundaryReceiver interface {
+	AudioBoundary()
+}
+
+// AudioResponsePipe writers implementing this get every alignment event after the audio it describes,
+// before the delivery policy can drop it, e.g. to record the stream
+type AudioAlignmentReceiver interface {
+	AudioAlignment(StreamingOutputResponse)
+}
+
+// Query setting this format as output_format
+func (f AudioFormat) Query() QueryFunc {
+	return OutputFormat(string(f))
+}
+
+func (f AudioFormat) Info() (AudioFormatInfo, error) {
+	return ParseAudioFormat(string(f))
+}
+
+// Parse an output_format value such as "pcm_16000" or "mp3_44100_128"
+func ParseAudioFormat(value string) (AudioFormatInfo, error) {
+	parts := strings.Split(value, "_")
+	if len(parts) < 2 || len(parts) > 3 {
+		return AudioFormatInfo{}, fmt.Errorf("invalid audio format %q", value)
+	}
+
+	rate, err := strconv.Atoi(parts[1])
+	if err != nil || rate <= 0 {
+		return AudioFormatInfo{}, fmt.Errorf("invalid sample rate in audio format %q", value)
+	}
+	info := AudioFormatInfo{Format: AudioFormat(value), SampleRate: rate, Channels: 1}
+
+	switch parts[0] {
+	case "mp3":
+		info.Encoding = EncodingMP3
+	case "opus":
+		info.Encoding = EncodingOpus
+	case "pcm":
+		info.Encoding = EncodingPCM
+		info.BitsPerSample = 16
+	case "ulaw":
+		info.Encoding = EncodingULAW
+		info.BitsPerSample = 8
+	case "alaw":
+		info.Encoding = EncodingALAW
+		info.BitsPerSample = 8
+	default:
+		return AudioFormatInfo{}, fmt.Errorf("unknown encoding in audio format %q", value)
+	}
+
+	if info.Compressed() {
+		if len(parts) != 3 {
+			return AudioFormatInfo{}, fmt.Errorf("missing bitrate in audio format %q", value)
+		}
+		if info.Bitrate, err = strconv.Atoi(parts[2]); err != nil || info.Bitrate <= 0 {
+			return AudioFormatInfo{}, fmt.Errorf("invalid bitrate in audio format %q", value)
+		}
+	} else if len(parts) != 2 {
+		return AudioFormatInfo{}, fmt.Errorf("unexpected bitrate in audio format %q", value)
+	}
+
+	return info, nil
+}
+
+// Format selected by a set of queries, DEFAULT_OUTPUT_FORMAT when none sets output_format
+func ResolveAudioFormat(queries ...QueryFunc) (AudioFormatInfo, error) {
+	q := neturl.Values{}
+	for _, qf := range queries {
+		qf(&q)
+	}
+	if v := q.Get("output_format"); v != "" {
+		return ParseAudioFormat(v)
+	}
+	return DEFAULT_OUTPUT_FORMAT.Info()
+}
+
+func (i AudioFormatInfo) Compressed() bool {
+	return i.Encoding == EncodingMP3 || i.Encoding == EncodingOpus
+}
+
+// Bytes per sample frame across all channels, 0 for compressed encodings
+func (i AudioFormatInfo) FrameSize() int {
+	return i.BitsPerSample / 8 * i.Channels
+}
+
+// Data rate, exact for PCM and G.711 and nominal for compressed encodings
+func (i AudioFormatInfo) BytesPerSecond() int {
+	if i.Compressed() {
+		return i.Bitrate * 1000 / 8
+	}
+	return i.SampleRate * i.FrameSize()
+}
+
+// Playback duration of n bytes of audio in this format
+func (i AudioFormatInfo) Duration(n int) time.Duration {
+	bps := i.BytesPerSecond()
+	if bps == 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second / time.Duration(bps)
+}
+
+// Tell w the stream format if it wants to know
+func announceFormat(w any, info AudioFormatInfo, err error) {
+	if r, ok := w.(AudioFormatReceiver); ok && err == nil {
+		r.SetAudioFormat(info)
+	}
+}
+
+// Hand w an alignment event if it wants every one
+func passAlignment(w any, resp StreamingOutputResponse) {
+	if r, ok := w.(AudioAlignmentReceiver); ok {
+		r.AudioAlignment(resp)
+	}
+}
+
+// Tell w that the audio written so far completes a generation
+func markBoundary(w any) {
+	if r, ok := w.(AudioBoundaryReceiver); ok {
+		r.AudioBoundary()
+	}
+}
@@ -0,0 +1,104 @@
+package elevenlabs_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+func TestParseAudioFormat(t *testing.T) {
+	tests := []struct {
+		value string
+		want  elevenlabs.AudioFormatInfo
+	}{
+		{"pcm_16000", elevenlabs.AudioFormatInfo{Encoding: elevenlabs.EncodingPCM, SampleRate: 16000, Channels: 1, BitsPerSample: 16}},
+		{"ulaw_8000", elevenlabs.AudioFormatInfo{Encoding: elevenlabs.EncodingULAW, SampleRate: 8000, Channels: 1, BitsPerSample: 8}},
+		{"alaw_8000", elevenlabs.AudioFormatInfo{Encoding: elevenlabs.EncodingALAW, SampleRate: 8000, Channels: 1, BitsPerSample: 8}},
+		{"mp3_44100_128", elevenlabs.AudioFormatInfo{Encoding: elevenlabs.EncodingMP3, SampleRate: 44100, Channels: 1, Bitrate: 128}},
+		{"opus_48000_32", elevenlabs.AudioFormatInfo{Encoding: elevenlabs.EncodingOpus, SampleRate: 48000, Channels: 1, Bitrate: 32}},
+	}
+	for _, tt := range tests {
+		got, err := elevenlabs.ParseAudioFormat(tt.value)
+		tt.want.Format = elevenlabs.AudioFormat(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseAudioFormat(%q) = %+v, %v", tt.value, got, err)
+		}
+	}
+
+	for _, value := range []string{"", "pcm", "pcm_x", "pcm_0", "flac_44100", "mp3_44100", "mp3_44100_0", "pcm_16000_64", "mp3_44100_128_1"} {
+		if _, err := elevenlabs.ParseAudioFormat(value); err == nil {
+			t.Errorf("ParseAudioFormat(%q) succeeded", value)
+		}
+	}
+}
+
+func TestResolveAudioFormat(t *testing.T) {
+	if f, err := elevenlabs.ResolveAudioFormat(elevenlabs.LanguageCode("en")); err != nil || f.Format != elevenlabs.DEFAULT_OUTPUT_FORMAT {
+		t.Errorf("default format = %+v, %v", f, err)
+	}
+	if f, err := elevenlabs.ResolveAudioFormat(elevenlabs.FormatULAW_8000.Query()); err != nil || f.Encoding != elevenlabs.EncodingULAW {
+		t.Errorf("ulaw format = %+v, %v", f, err)
+	}
+}
+
+func TestAudioFormatDuration(t *testing.T) {
+	tests := []struct {
+		format elevenlabs.AudioFormat
+		bytes  int
+		want   time.Duration
+	}{
+		{elevenlabs.FormatPCM_16000, 32000, time.Second},
+		{elevenlabs.FormatPCM_24000, 480, 10 * time.Millisecond},
+		{elevenlabs.FormatULAW_8000, 160, 20 * time.Millisecond},
+		{elevenlabs.FormatMP3_44100_128, 16000, time.Second},
+	}
+	for _, tt := range tests {
+		info, _ := tt.format.Info()
+		if got := info.Duration(tt.bytes); got != tt.want {
+			t.Errorf("%s: Duration(%d) = %v, want %v", tt.format, tt.bytes, got, tt.want)
+		}
+	}
+	if got := (elevenlabs.AudioFormatInfo{}).Duration(100); got != 0 {
+		t.Errorf("unknown format duration = %v", got)
+	}
+}
+
+// Writer recording the format and boundaries it is told about between audio writes
+type formatRecorder struct {
+	audioBuffer
+}
+
+func (r *formatRecorder) SetAudioFormat(info elevenlabs.AudioFormatInfo) {
+	fmt.Fprintf(&r.audioBuffer, "[%s]", info.Format)
+}
+
+func (r *formatRecorder) AudioBoundary() {
+	io.WriteString(&r.audioBuffer, "|")
+}
+
+func TestStreamingRequestAnnouncesFormatAndBoundaries(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+
+	text := make(chan string, 5)
+	for _, s := range []string{"one ", elevenlabs.FLUSH_MARKER, "two", elevenlabs.CLOSURE_MARKER} {
+		text <- s
+	}
+	var rec formatRecorder
+	done := make(chan error, 1)
+	go func() {
+		done <- c.StreamingRequest(text, nil, &rec, "voice", "model", elevenlabs.TextToSpeechInputStreamingRequest{},
+			elevenlabs.FormatPCM_16000.Query())
+	}()
+	if err := wait(t, done); err != nil {
+		t.Fatalf("StreamingRequest = %v", err)
+	}
+	if got := rec.String(); got != "[pcm_16000]one |two|" {
+		t.Errorf("writer saw %q", got)
+	}
+}
@@ -0,0 +1,108 @@
+// Package g711 converts streaming TTS audio for telephony: PCM16 <-> μ-law <-> A-law, resampled to 8 kHz.
+package g711
+
+const ULAW_BIAS = 0x84
+const ULAW_CLIP = 32635
+
+var ulawDecodeTable [256]int16
+var alawDecodeTable [256]int16
+
+func init() {
+	for i := range 256 {
+		ulawDecodeTable[i] = decodeULaw(byte(i))
+		alawDecodeTable[i] = decodeALaw(byte(i))
+	}
+}
+
+// Encode a linear PCM16 sample as G.711 μ-law
+func EncodeULaw(sample int16) byte {
+	s := int(sample)
+	sign := 0
+	if s < 0 {
+		s = -s
+		sign = 0x80
+	}
+	if s > ULAW_CLIP {
+		s = ULAW_CLIP
+	}
+	s += ULAW_BIAS
+
+	exponent := 7
+	for mask := 0x4000; s&mask == 0 && exponent > 0; mask >>= 1 {
+		exponent--
+	}
+	mantissa := (s >> (exponent + 3)) & 0x0F
+	return ^byte(sign | exponent<<4 | mantissa)
+}
+
+func DecodeULaw(b byte) int16 {
+	return ulawDecodeTable[b]
+}
+
+func decodeULaw(b byte) int16 {
+	b = ^b
+	sign := b & 0x80
+	exponent := int(b>>4) & 0x07
+	mantissa := int(b & 0x0F)
+	s := ((mantissa << 3) + ULAW_BIAS) << exponent
+	s -= ULAW_BIAS
+	if sign != 0 {
+		return int16(-s)
+	}
+	return int16(s)
+}
+
+// Encode a linear PCM16 sample as G.711 A-law
+func EncodeALaw(sample int16) byte {
+	s := int(sample)
+	sign := 0x80
+	if s < 0 {
+		s = -s - 1
+		sign = 0
+	}
+	if s > 32767 {
+		s = 32767
+	}
+
+	var b int
+	if s < 256 {
+		b = s >> 4
+	} else {
+		exponent := 7
+		for mask := 0x4000; s&mask == 0 && exponent > 1; mask >>= 1 {
+			exponent--
+		}
+		b = exponent<<4 | (s>>(exponent+3))&0x0F
+	}
+	return byte(sign|b) ^ 0x55
+}
+
+func DecodeALaw(b byte) int16 {
+	return alawDecodeTable[b]
+}
+
+func decodeALaw(b byte) int16 {
+	b ^= 0x55
+	exponent := int(b>>4) & 0x07
+	mantissa := int(b & 0x0F)
+
+	var s int
+	if exponent == 0 {
+		s = mantissa<<4 + 8
+	} else {
+		s = (mantissa<<4 + 0x108) << (exponent - 1)
+	}
+	if b&0x80 == 0 {
+		return int16(-s)
+	}
+	return int16(s)
+}
+
+// Direct μ-law to A-law conversion through linear PCM
+func ULawToALaw(b byte) byte {
+	return EncodeALaw(DecodeULaw(b))
+}
+
+func ALawToULaw(b byte) byte {
+	return EncodeULaw(DecodeALaw(b))
+}
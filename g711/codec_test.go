@@ -0,0 +1,82 @@
+package g711_test
+
+import (
+	"testing"
+
+	"github.com/clearlyip/elevenlabs-go-websockets/g711"
+)
+
+func TestULawRoundTrip(t *testing.T) {
+	for i := range 256 {
+		b := byte(i)
+		got := g711.EncodeULaw(g711.DecodeULaw(b))
+		// Negative zero decodes to 0, which encodes as positive zero
+		if b == 0x7F {
+			b = 0xFF
+		}
+		if got != b {
+			t.Errorf("EncodeULaw(DecodeULaw(%#02x)) = %#02x", i, got)
+		}
+	}
+}
+
+func TestALawRoundTrip(t *testing.T) {
+	for i := range 256 {
+		if got := g711.EncodeALaw(g711.DecodeALaw(byte(i))); got != byte(i) {
+			t.Errorf("EncodeALaw(DecodeALaw(%#02x)) = %#02x", i, got)
+		}
+	}
+}
+
+func TestEncodeKnownValues(t *testing.T) {
+	tests := []struct {
+		sample int16
+		ulaw   byte
+		alaw   byte
+	}{
+		{0, 0xFF, 0xD5},
+		{-1, 0x7F, 0x55},
+		{32767, 0x80, 0xAA},
+		{-32768, 0x00, 0x2A},
+	}
+	for _, tt := range tests {
+		if got := g711.EncodeULaw(tt.sample); got != tt.ulaw {
+			t.Errorf("EncodeULaw(%d) = %#02x, want %#02x", tt.sample, got, tt.ulaw)
+		}
+		if got := g711.EncodeALaw(tt.sample); got != tt.alaw {
+			t.Errorf("EncodeALaw(%d) = %#02x, want %#02x", tt.sample, got, tt.alaw)
+		}
+	}
+}
+
+// Companding keeps the error within the segment step, about 1/16 of the magnitude
+func TestQuantizationError(t *testing.T) {
+	for s := -32000; s <= 32000; s += 7 {
+		bound := abs(s)/16 + 16
+		if got := int(g711.DecodeULaw(g711.EncodeULaw(int16(s)))); abs(got-s) > bound {
+			t.Fatalf("μ-law %d decodes as %d", s, got)
+		}
+		if got := int(g711.DecodeALaw(g711.EncodeALaw(int16(s)))); abs(got-s) > bound {
+			t.Fatalf("A-law %d decodes as %d", s, got)
+		}
+	}
+}
+
+func TestLawConversion(t *testing.T) {
+	for i := range 256 {
+		b := byte(i)
+		if got, want := g711.ULawToALaw(b), g711.EncodeALaw(g711.DecodeULaw(b)); got != want {
+			t.Errorf("ULawToALaw(%#02x) = %#02x, want %#02x", b, got, want)
+		}
+		if got, want := g711.ALawToULaw(b), g711.EncodeULaw(g711.DecodeALaw(b)); got != want {
+			t.Errorf("ALawToULaw(%#02x) = %#02x, want %#02x", b, got, want)
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
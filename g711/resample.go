@@ -0,0 +1,40 @@
+package g711
+
+// Streaming PCM16 decimator, each output sample averages the input samples it covers
+type Resampler struct {
+	from int
+	to   int
+	acc  int // Input weight collected towards the next output sample, in units of 1/to samples
+	sum  int // Weighted sum for the next output sample
+}
+
+// Resampler from one sample rate down to another, from must not be below to
+func NewResampler(from int, to int) *Resampler {
+	return &Resampler{from: from, to: to}
+}
+
+// Append resampled samples for in to out, state carries over between calls
+func (r *Resampler) Resample(out []int16, in []int16) []int16 {
+	if r.from == r.to {
+		return append(out, in...)
+	}
+
+	// Every input sample weighs to, every output sample needs from
+	for _, s := range in {
+		weight := r.to
+		if r.acc+weight >= r.from {
+			part := r.from - r.acc
+			r.sum += int(s) * part
+			out = append(out, int16(r.sum/r.from))
+			weight -= part
+			r.acc, r.sum = 0, 0
+		}
+		r.acc += weight
+		r.sum += int(s) * weight
+	}
+	return out
+}
+
+func (r *Resampler) Reset() {
+	r.acc, r.sum = 0, 0
+}
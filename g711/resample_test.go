@@ -0,0 +1,42 @@
+package g711_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/clearlyip/elevenlabs-go-websockets/g711"
+)
+
+func TestResamplerLength(t *testing.T) {
+	for _, from := range []int{8000, 16000, 22050, 24000, 44100, 48000} {
+		in := make([]int16, from) // One second
+		out := g711.NewResampler(from, g711.SAMPLE_RATE).Resample(nil, in)
+		if len(out) != g711.SAMPLE_RATE {
+			t.Errorf("%d Hz: %d samples, want %d", from, len(out), g711.SAMPLE_RATE)
+		}
+	}
+}
+
+func TestResamplerAverages(t *testing.T) {
+	out := g711.NewResampler(24000, 8000).Resample(nil, []int16{3, 6, 9, 100, 100, 100, -30, 0, 30})
+	if want := []int16{6, 100, 0}; !slices.Equal(out, want) {
+		t.Errorf("Resample = %v, want %v", out, want)
+	}
+}
+
+func TestResamplerCarriesStateAcrossCalls(t *testing.T) {
+	in := make([]int16, 4410)
+	for i := range in {
+		in[i] = int16(i*37%2000 - 1000)
+	}
+	whole := g711.NewResampler(44100, 8000).Resample(nil, in)
+
+	r := g711.NewResampler(44100, 8000)
+	var split []int16
+	for chunk := range slices.Chunk(in, 101) {
+		split = r.Resample(split, chunk)
+	}
+	if !slices.Equal(whole, split) {
+		t.Error("chunked resampling differs from a single call")
+	}
+}
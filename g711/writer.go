@@ -0,0 +1,149 @@
+package g711
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+// Telephony sample rate
+const SAMPLE_RATE = 8000
+
+// io.Writer transcoding streaming TTS audio to 8 kHz μ-law, A-law or PCM16.
+// Use it as AudioResponsePipe, the streaming request reports the source format through SetAudioFormat
+// and generation ends through AudioBoundary, which reach the wrapped writer when it takes them.
+type Writer struct {
+	w      io.Writer
+	target elevenlabs.AudioEncoding
+	source elevenlabs.AudioFormatInfo
+	err    error // Unsupported source format
+
+	resampler *Resampler
+	odd       []byte // Half of a PCM16 sample split across writes
+	samples   []int16
+	out       []byte
+}
+
+// Writer encoding to target, one of EncodingULAW, EncodingALAW or EncodingPCM
+func NewWriter(w io.Writer, target elevenlabs.AudioEncoding) (*Writer, error) {
+	switch target {
+	case elevenlabs.EncodingULAW, elevenlabs.EncodingALAW, elevenlabs.EncodingPCM:
+	default:
+		return nil, fmt.Errorf("unsupported target encoding %q", target)
+	}
+	return &Writer{w: w, target: target, err: fmt.Errorf("audio format not set")}, nil
+}
+
+// Source audio format, PCM at 8 kHz or above or G.711 at 8 kHz
+func (w *Writer) SetAudioFormat(info elevenlabs.AudioFormatInfo) {
+	w.source = info
+	w.odd = w.odd[:0]
+	w.err = nil
+
+	switch {
+	case info.Channels != 1:
+		w.err = fmt.Errorf("unsupported channel count %d", info.Channels)
+	case info.Encoding == elevenlabs.EncodingPCM && info.SampleRate >= SAMPLE_RATE:
+		w.resampler = NewResampler(info.SampleRate, SAMPLE_RATE)
+	case (info.Encoding == elevenlabs.EncodingULAW || info.Encoding == elevenlabs.EncodingALAW) && info.SampleRate == SAMPLE_RATE:
+		w.resampler = nil
+	default:
+		w.err = fmt.Errorf("unsupported source format %q", info.Format)
+	}
+	if w.err != nil {
+		return
+	}
+
+	if r, ok := w.w.(elevenlabs.AudioFormatReceiver); ok {
+		r.SetAudioFormat(w.Format())
+	}
+}
+
+// End of a generation, passed on to the wrapped writer
+func (w *Writer) AudioBoundary() {
+	if r, ok := w.w.(elevenlabs.AudioBoundaryReceiver); ok {
+		r.AudioBoundary()
+	}
+}
+
+// Format of the audio written to the wrapped writer
+func (w *Writer) Format() elevenlabs.AudioFormatInfo {
+	var format elevenlabs.AudioFormat
+	switch w.target {
+	case elevenlabs.EncodingULAW:
+		format = elevenlabs.FormatULAW_8000
+	case elevenlabs.EncodingALAW:
+		format = elevenlabs.FormatALAW_8000
+	default:
+		format = elevenlabs.FormatPCM_8000
+	}
+	info, _ := format.Info()
+	return info
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	w.out = w.out[:0]
+	switch w.source.Encoding {
+	case elevenlabs.EncodingPCM:
+		w.samples = w.resampler.Resample(w.samples[:0], w.pcmSamples(p))
+		w.out = w.encode(w.out, w.samples)
+	case elevenlabs.EncodingULAW:
+		w.out = w.transcode(w.out, p, DecodeULaw)
+	case elevenlabs.EncodingALAW:
+		w.out = w.transcode(w.out, p, DecodeALaw)
+	}
+
+	if len(w.out) > 0 {
+		if _, err := w.w.Write(w.out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Little endian samples in p, keeping a trailing odd byte for the next write
+func (w *Writer) pcmSamples(p []byte) []int16 {
+	samples := make([]int16, 0, (len(w.odd)+len(p))/2)
+	if len(w.odd) == 1 && len(p) > 0 {
+		samples = append(samples, int16(uint16(w.odd[0])|uint16(p[0])<<8))
+		w.odd = w.odd[:0]
+		p = p[1:]
+	}
+	for ; len(p) >= 2; p = p[2:] {
+		samples = append(samples, int16(binary.LittleEndian.Uint16(p)))
+	}
+	w.odd = append(w.odd, p...)
+	return samples
+}
+
+func (w *Writer) encode(out []byte, samples []int16) []byte {
+	for _, s := range samples {
+		switch w.target {
+		case elevenlabs.EncodingULAW:
+			out = append(out, EncodeULaw(s))
+		case elevenlabs.EncodingALAW:
+			out = append(out, EncodeALaw(s))
+		default:
+			out = binary.LittleEndian.AppendUint16(out, uint16(s))
+		}
+	}
+	return out
+}
+
+// G.711 source, passed through when the law already matches
+func (w *Writer) transcode(out []byte, p []byte, decode func(byte) int16) []byte {
+	if w.source.Encoding == w.target {
+		return append(out, p...)
+	}
+	w.samples = w.samples[:0]
+	for _, b := range p {
+		w.samples = append(w.samples, decode(b))
+	}
+	return w.encode(out, w.samples)
+}
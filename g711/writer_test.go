@@ -0,0 +1,98 @@
+package g711_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/clearlyip/elevenlabs-go-websockets/g711"
+)
+
+func info(t *testing.T, f elevenlabs.AudioFormat) elevenlabs.AudioFormatInfo {
+	t.Helper()
+	i, err := f.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func pcm(samples ...int16) []byte {
+	var b []byte
+	for _, s := range samples {
+		b = binary.LittleEndian.AppendUint16(b, uint16(s))
+	}
+	return b
+}
+
+func TestWriterRequiresFormat(t *testing.T) {
+	w, err := g711.NewWriter(&bytes.Buffer{}, elevenlabs.EncodingULAW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte{1, 2}); err == nil {
+		t.Error("Write before SetAudioFormat succeeded")
+	}
+
+	w.SetAudioFormat(info(t, elevenlabs.FormatMP3_44100_128))
+	if _, err := w.Write([]byte{1, 2}); err == nil {
+		t.Error("Write of mp3 audio succeeded")
+	}
+
+	if _, err := g711.NewWriter(&bytes.Buffer{}, elevenlabs.EncodingMP3); err == nil {
+		t.Error("NewWriter accepted an mp3 target")
+	}
+}
+
+func TestWriterPCMToULaw(t *testing.T) {
+	var out bytes.Buffer
+	w, _ := g711.NewWriter(&out, elevenlabs.EncodingULAW)
+	w.SetAudioFormat(info(t, elevenlabs.FormatPCM_16000))
+
+	// Samples split mid-way through a sample
+	audio := pcm(1000, 1000, -2000, -2000, 0, 0)
+	for _, chunk := range [][]byte{audio[:3], audio[3:7], audio[7:]} {
+		if n, err := w.Write(chunk); err != nil || n != len(chunk) {
+			t.Fatalf("Write = %d, %v", n, err)
+		}
+	}
+
+	want := []byte{g711.EncodeULaw(1000), g711.EncodeULaw(-2000), g711.EncodeULaw(0)}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("output = %x, want %x", out.Bytes(), want)
+	}
+	if f := w.Format(); f.Format != elevenlabs.FormatULAW_8000 {
+		t.Errorf("Format = %s", f.Format)
+	}
+}
+
+func TestWriterG711Sources(t *testing.T) {
+	in := []byte{0x00, 0x7F, 0x80, 0xFF}
+
+	var same bytes.Buffer
+	w, _ := g711.NewWriter(&same, elevenlabs.EncodingULAW)
+	w.SetAudioFormat(info(t, elevenlabs.FormatULAW_8000))
+	w.Write(in)
+	if !bytes.Equal(same.Bytes(), in) {
+		t.Errorf("μ-law passthrough = %x", same.Bytes())
+	}
+
+	var alaw bytes.Buffer
+	w, _ = g711.NewWriter(&alaw, elevenlabs.EncodingALAW)
+	w.SetAudioFormat(info(t, elevenlabs.FormatULAW_8000))
+	w.Write(in)
+	for i, b := range in {
+		if got, want := alaw.Bytes()[i], g711.ULawToALaw(b); got != want {
+			t.Errorf("byte %d = %#02x, want %#02x", i, got, want)
+		}
+	}
+
+	var linear bytes.Buffer
+	w, _ = g711.NewWriter(&linear, elevenlabs.EncodingPCM)
+	w.SetAudioFormat(info(t, elevenlabs.FormatALAW_8000))
+	w.Write([]byte{0xD5})
+	if !bytes.Equal(linear.Bytes(), pcm(g711.DecodeALaw(0xD5))) {
+		t.Errorf("A-law to PCM = %x", linear.Bytes())
+	}
+}
@@ -3,6 +3,8 @@ module github.com/clearlyip/elevenlabs-go-websockets
 go 1.23.2
 
 require (
-	github.com/gorilla/websocket v1.5.3 // indirect
-	github.com/nrednav/cuid2 v1.0.1 // indirect
+	github.com/gorilla/websocket v1.5.3
+	github.com/nrednav/cuid2 v1.0.1
 )
+
+require golang.org/x/crypto v0.17.0 // indirect
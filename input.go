@@ -0,0 +1,94 @@
+package elevenlabs
+
+// Kind of an InputEvent
+type InputKind int
+
+const (
+	InputText                InputKind = iota // Text chunk to synthesize
+	InputFlush                                // Generate audio for buffered text
+	InputCloseContext                         // Finish the request's context, the socket closes after its audio
+	InputCloseSocket                          // Finish all contexts and close the socket
+	InputUpdateVoiceSettings                  // Replace the voice settings from the next text chunk on
+)
+
+func (k InputKind) String() string {
+	switch k {
+	case InputText:
+		return "text"
+	case InputFlush:
+		return "flush"
+	case InputCloseContext:
+		return "close_context"
+	case InputCloseSocket:
+		return "close_socket"
+	case InputUpdateVoiceSettings:
+		return "update_voice_settings"
+	}
+	return "unknown"
+}
+
+// Typed input for StreamingRequestEvents and MultiCtxStreamingRequestEvents
+type InputEvent struct {
+	Kind          InputKind
+	Text          string          // InputText
+	VoiceSettings *VoiceSettings  // InputUpdateVoiceSettings
+	Overrides     *ChunkOverrides // InputText, optional
+}
+
+func TextInput(text string) InputEvent {
+	return InputEvent{Kind: InputText, Text: text}
+}
+
+func FlushInput() InputEvent {
+	return InputEvent{Kind: InputFlush}
+}
+
+func CloseContextInput() InputEvent {
+	return InputEvent{Kind: InputCloseContext}
+}
+
+func CloseSocketInput() InputEvent {
+	return InputEvent{Kind: InputCloseSocket}
+}
+
+func VoiceSettingsInput(settings *VoiceSettings) InputEvent {
+	return InputEvent{Kind: InputUpdateVoiceSettings, VoiceSettings: settings}
+}
+
+// Translate a TextReader using CLOSURE_MARKER and FLUSH_MARKER into input events until stop is closed
+func markerEvents(text <-chan string, stop <-chan struct{}) <-chan InputEvent {
+	events := make(chan InputEvent)
+	go func() {
+		defer close(events)
+		for {
+			var chunk string
+			var ok bool
+			select {
+			case chunk, ok = <-text:
+				if !ok {
+					return
+				}
+			case <-stop:
+				return
+			}
+
+			var batch []InputEvent
+			switch chunk {
+			case CLOSURE_MARKER:
+				batch = []InputEvent{FlushInput(), CloseSocketInput()}
+			case FLUSH_MARKER:
+				batch = []InputEvent{FlushInput()}
+			default:
+				batch = []InputEvent{TextInput(chunk)}
+			}
+			for _, ev := range batch {
+				select {
+				case events <- ev:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+	return events
+}
@@ -0,0 +1,103 @@
+package elevenlabs_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/clearlyip/elevenlabs-go-websockets/elevenlabstest"
+)
+
+// Frames the client sent after connecting, one word per frame
+func sentFrames(srv *elevenlabstest.Server) []string {
+	var out []string
+	for _, r := range srv.Requests() {
+		f := r.Frame
+		switch {
+		case f.CloseSocket:
+			out = append(out, "close_socket")
+		case f.CloseContext:
+			out = append(out, "close_context")
+		case f.Flush:
+			out = append(out, "flush")
+		case f.Text == " ":
+			out = append(out, "init")
+		case f.VoiceSettings != nil:
+			out = append(out, f.Text+"+voice")
+		default:
+			out = append(out, f.Text)
+		}
+	}
+	return out
+}
+
+func TestStreamingRequestEvents(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+
+	input := make(chan elevenlabs.InputEvent, 8)
+	for _, ev := range []elevenlabs.InputEvent{
+		elevenlabs.TextInput("hello "),
+		elevenlabs.FlushInput(),
+		elevenlabs.VoiceSettingsInput(&elevenlabs.VoiceSettings{Stability: 0.3, SimilarityBoost: 0.6}),
+		elevenlabs.TextInput("world "),
+		elevenlabs.TextInput("again"),
+		elevenlabs.CloseContextInput(),
+	} {
+		input <- ev
+	}
+	var audio audioBuffer
+	if err := c.StreamingRequestEvents(input, nil, &audio, "voice", "model", elevenlabs.TextToSpeechInputStreamingRequest{}); err != nil {
+		t.Fatalf("StreamingRequestEvents = %v", err)
+	}
+	if got := audio.String(); got != "hello world again" {
+		t.Errorf("audio = %q", got)
+	}
+
+	// Voice settings go out with the next text chunk only
+	want := []string{"init", "hello ", "flush", "world +voice", "again", "close_context", "close_socket"}
+	if got := sentFrames(srv); !slices.Equal(got, want) {
+		t.Errorf("frames = %q, want %q", got, want)
+	}
+}
+
+func TestStreamingRequestMarkers(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+
+	text := make(chan string, 4)
+	for _, s := range []string{"hello ", elevenlabs.FLUSH_MARKER, "world", elevenlabs.CLOSURE_MARKER} {
+		text <- s
+	}
+	var audio audioBuffer
+	if err := wait(t, stream(c, text, nil, &audio)); err != nil {
+		t.Fatalf("StreamingRequest = %v", err)
+	}
+	want := []string{"init", "hello ", "flush", "world", "flush", "close_socket"}
+	if got := sentFrames(srv); !slices.Equal(got, want) {
+		t.Errorf("frames = %q, want %q", got, want)
+	}
+}
+
+func TestMultiCtxStreamingRequestEventsCloseSocket(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := elevenlabs.NewMultiClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+
+	input := make(chan elevenlabs.InputEvent, 2)
+	input <- elevenlabs.TextInput("hello")
+	input <- elevenlabs.CloseSocketInput()
+	var audio audioBuffer
+	if err := c.MultiCtxStreamingRequestEvents(input, nil, &audio, "voice", "model"); err != nil {
+		t.Fatalf("MultiCtxStreamingRequestEvents = %v", err)
+	}
+	// Initialization context opened and closed first
+	want := []string{"init", "close_context", "hello", "close_socket"}
+	if got := sentFrames(srv); !slices.Equal(got, want) || audio.String() != "hello" {
+		t.Errorf("frames = %q, audio %q", got, audio.String())
+	}
+}
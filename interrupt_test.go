@@ -0,0 +1,158 @@
+package elevenlabs_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/clearlyip/elevenlabs-go-websockets/elevenlabstest"
+)
+
+// 50ms of 16kHz PCM per character, matching the alignment timings
+const PCM_BYTES_PER_CHAR = 1600
+
+func pcmServer(t *testing.T) *elevenlabstest.Server {
+	return newServer(t,
+		elevenlabstest.WithCharDuration(50*time.Millisecond),
+		elevenlabstest.WithAudio(func(text string) []byte { return make([]byte, PCM_BYTES_PER_CHAR*len([]rune(text))) }),
+	)
+}
+
+func pcmSession(t *testing.T, srv *elevenlabstest.Server) *elevenlabs.MultiClient {
+	t.Helper()
+	c, err := elevenlabs.NewMultiContextSession(context.Background(), "key", time.Second, "voice", "model",
+		[]elevenlabs.QueryFunc{elevenlabs.FormatPCM_16000.Query()}, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// Wait until the buffer holds n bytes
+func waitAudio(t *testing.T, audio *audioBuffer, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(audio.String()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d audio bytes received", len(audio.String()), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	checkGoroutines(t)
+	srv := pcmServer(t)
+	c := pcmSession(t, srv)
+
+	var audio audioBuffer
+	if err := c.OpenContext("a", nil, elevenlabs.ContextSink{Audio: &audio}); err != nil {
+		t.Fatal(err)
+	}
+	c.SendText("a", "hello big")
+	waitAudio(t, &audio, 9*PCM_BYTES_PER_CHAR)
+
+	// The closure is confirmed late, the id stays reserved until then
+	srv.Inject(elevenlabstest.Delay(1, 100*time.Millisecond))
+	result, err := c.InterruptAt("a", 320*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ContextID != "a" || result.PlayedMs != 320 || result.Chars != 6 || result.Text != "hello " {
+		t.Errorf("result = %+v", result)
+	}
+	if len(result.Words) != 1 || result.Words[0].Word != "hello" {
+		t.Errorf("played words = %+v", result.Words)
+	}
+
+	if err := c.SendText("a", "world"); err == nil {
+		t.Error("SendText accepted on an interrupted context")
+	}
+	if err := c.OpenContext("a", nil, elevenlabs.ContextSink{}); err == nil || !strings.Contains(err.Error(), "still closing") {
+		t.Errorf("reopening before isFinal = %v", err)
+	}
+	// The slot is free straight away
+	for i := range elevenlabs.MULTI_CONTEXT_MAX_REQUESTS {
+		id := string(rune('b' + i))
+		if err := c.OpenContext(id, nil, elevenlabs.ContextSink{}); err != nil {
+			t.Fatalf("OpenContext(%s) after an interrupt = %v", id, err)
+		}
+		if i > 0 {
+			c.CloseContext(id)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for c.OpenContext("a", nil, elevenlabs.ContextSink{Audio: &audio}) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("interrupted id never released")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestInterruptDropsLateAudio(t *testing.T) {
+	checkGoroutines(t)
+	srv := pcmServer(t)
+	c := pcmSession(t, srv)
+
+	var audio audioBuffer
+	if err := c.OpenContext("a", nil, elevenlabs.ContextSink{Audio: &audio}); err != nil {
+		t.Fatal(err)
+	}
+	c.SendText("a", "hello ")
+	waitAudio(t, &audio, 6*PCM_BYTES_PER_CHAR)
+
+	// Audio for this chunk is still in flight when the context is interrupted
+	srv.Inject(elevenlabstest.Delay(1, 100*time.Millisecond))
+	c.SendText("a", "world")
+	result, err := c.Interrupt("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PlayedMs != 300 || result.Text != "hello " {
+		t.Errorf("result = %+v", result)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if n := len(audio.String()); n != 6*PCM_BYTES_PER_CHAR {
+		t.Errorf("%d audio bytes after the interrupt, want %d", n, 6*PCM_BYTES_PER_CHAR)
+	}
+	if _, err := c.Interrupt("unknown"); err == nil {
+		t.Error("unknown context interrupted")
+	}
+}
+
+// Audio running past the alignment of its chunk moves the following chunks along
+func TestInterruptAfterTrailingSilence(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t,
+		elevenlabstest.WithCharDuration(50*time.Millisecond),
+		elevenlabstest.WithAudio(func(text string) []byte { return make([]byte, 2*PCM_BYTES_PER_CHAR*len([]rune(text))) }),
+	)
+	c := pcmSession(t, srv)
+
+	var audio audioBuffer
+	alignment := make(chan elevenlabs.StreamingOutputMultiCtxResponse, 16)
+	if err := c.OpenContext("a", nil, elevenlabs.ContextSink{Audio: &audio, Alignment: alignment}); err != nil {
+		t.Fatal(err)
+	}
+	c.SendText("a", "one ")
+	c.SendText("a", "two")
+	waitAudio(t, &audio, 7*2*PCM_BYTES_PER_CHAR)
+	if resp := <-alignment; resp.AudioDurationMs != 400 {
+		t.Errorf("first chunk audio = %d ms, want 400", resp.AudioDurationMs)
+	}
+
+	// "two" starts at 400 ms, after the silence closing "one "
+	result, err := c.InterruptAt("a", 450*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Text != "one t" || len(result.Words) != 1 {
+		t.Errorf("played %q, words %+v", result.Text, result.Words)
+	}
+}
@@ -0,0 +1,43 @@
+package elevenlabs
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Handler used unless WithLogger is set, nothing is logged
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// Structured logger for connection, context and chunk events
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// Include conversation text in log records, only its length is logged by default
+func WithTextLogging(enabled bool) Option {
+	return func(o *options) {
+		o.logText = enabled
+	}
+}
+
+func (o *options) log() *slog.Logger {
+	if o.logger == nil {
+		return slog.New(discardHandler{})
+	}
+	return o.logger
+}
+
+// Text attribute, redacted unless WithTextLogging is enabled
+func (o *options) textAttr(text string) slog.Attr {
+	if o.logText {
+		return slog.String("text", text)
+	}
+	return slog.Int("text_len", len(text))
+}
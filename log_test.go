@@ -0,0 +1,60 @@
+package elevenlabs_test
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+// Log output of a streaming request for "secret text"
+func streamLog(t *testing.T, opts ...elevenlabs.Option) string {
+	t.Helper()
+	srv := newServer(t)
+	var out audioBuffer
+	logger := slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	opts = append([]elevenlabs.Option{elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()), elevenlabs.WithLogger(logger)}, opts...)
+	c := elevenlabs.NewClient(context.Background(), "api-key-value", time.Second, opts...)
+
+	text := make(chan string, 1)
+	text <- "secret text"
+	close(text)
+	var audio audioBuffer
+	if err := wait(t, stream(c, text, nil, &audio)); err != nil {
+		t.Fatalf("StreamingRequest = %v", err)
+	}
+	return out.String()
+}
+
+func TestLoggerRedactsText(t *testing.T) {
+	log := streamLog(t)
+	for _, want := range []string{`"voice_id":"voice"`, `"text_len":11`, `"msg":"first audio received"`, `"to":"draining"`} {
+		if !strings.Contains(log, want) {
+			t.Errorf("log misses %s:\n%s", want, log)
+		}
+	}
+	for _, secret := range []string{"secret text", "api-key-value"} {
+		if strings.Contains(log, secret) {
+			t.Errorf("log contains %q:\n%s", secret, log)
+		}
+	}
+
+	if log := streamLog(t, elevenlabs.WithTextLogging(true)); !strings.Contains(log, `"text":"secret text"`) {
+		t.Errorf("text not logged with WithTextLogging:\n%s", log)
+	}
+}
+
+func TestNoLogger(t *testing.T) {
+	// Without a logger nothing is written anywhere, the request works the same
+	srv := newServer(t)
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+	text := make(chan string)
+	close(text)
+	var audio audioBuffer
+	if err := wait(t, stream(c, text, nil, &audio)); err != nil {
+		t.Fatalf("StreamingRequest = %v", err)
+	}
+}
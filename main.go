@@ -6,14 +6,11 @@ package elevenlabs
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	neturl "net/url"
-	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -32,6 +29,8 @@ type Client struct {
 	apiKey  string
 	timeout time.Duration
 	ctx     context.Context
+	opts    options
+	dropped *atomic.Uint64 // Alignment events discarded by the delivery policy, shared with WithContext copies
 }
 
 type VoiceSettings struct {
@@ -39,6 +38,7 @@ type VoiceSettings struct {
 	Stability       float32 `json:"stability"`
 	Style           float32 `json:"style,omitempty"`
 	SpeakerBoost    bool    `json:"use_speaker_boost,omitempty"`
+	Speed           float32 `json:"speed,omitempty"`
 }
 
 type GenerationConfig struct {
@@ -52,26 +52,41 @@ type StreamingInputResponse struct {
 	IsFinal             bool                      `json:"isFinal"`
 	NormalizedAlignment StreamingAlignmentSegment `json:"normalizedAlignment"`
 	Alignment           StreamingAlignmentSegment `json:"alignment"`
+	Error               string                    `json:"error,omitempty"`   // Error frames only
+	Message             string                    `json:"message,omitempty"` // Error frames only
 }
 
 type StreamingOutputResponse struct {
 	IsFinal             bool                      `json:"isFinal"`
 	NormalizedAlignment StreamingAlignmentSegment `json:"normalizedAlignment"`
 	Alignment           StreamingAlignmentSegment `json:"alignment"`
+	AudioDurationMs     int                       `json:"audioDurationMs,omitempty"` // Audio of the frame, set by the client when the format is known
 }
 
 type StreamingOutputMultiCtxRawResponse struct {
+	Audio               string                    `json:"audio"`
 	IsFinal             bool                      `json:"isFinal"`
 	NormalizedAlignment StreamingAlignmentSegment `json:"normalizedAlignment"`
 	Alignment           StreamingAlignmentSegment `json:"alignment"`
 	ContextId           string                    `json:"contextId"`
 	ContextIdAlt        string                    `json:"context_id"`
+	Error               string                    `json:"error,omitempty"`   // Error frames only
+	Message             string                    `json:"message,omitempty"` // Error frames only
+}
+
+// Context the frame belongs to, the server uses both spellings
+func (r *StreamingOutputMultiCtxRawResponse) contextID() string {
+	if r.ContextId != "" {
+		return r.ContextId
+	}
+	return r.ContextIdAlt
 }
 
 type StreamingOutputMultiCtxResponse struct {
 	IsFinal             bool                      `json:"isFinal"`
 	NormalizedAlignment StreamingAlignmentSegment `json:"normalizedAlignment"`
 	Alignment           StreamingAlignmentSegment `json:"alignment"`
+	AudioDurationMs     int                       `json:"audioDurationMs,omitempty"` // Audio of the frame, set by the client when the format is known
 	ContextId           string                    `json:"contextId"`
 }
 
@@ -80,17 +95,40 @@ type TextToSpeechInputStreamingRequest struct {
 	ContextID        string            `json:"context_id,omitempty"`
 	VoiceSettings    *VoiceSettings    `json:"voice_settings,omitempty"`
 	GenerationConfig *GenerationConfig `json:"generation_config,omitempty"`
+
+	PronunciationDictionaryLocators []PronunciationDictionaryLocators `json:"pronunciation_dictionary_locators,omitempty"`
 }
 
 type WsStreamingOutputChannel chan StreamingOutputResponse
 
 // Standard Websocket Client
-func NewClient(ctx context.Context, apiKey string, reqTimeout time.Duration) *Client {
-	return &Client{apiKey: apiKey, timeout: reqTimeout, ctx: ctx}
+func NewClient(ctx context.Context, apiKey string, reqTimeout time.Duration, opts ...Option) *Client {
+	return &Client{apiKey: apiKey, timeout: reqTimeout, ctx: ctx, opts: newOptions(opts), dropped: new(atomic.Uint64)}
+}
+
+// Copy of the client bound to ctx, e.g. to cancel a single REST call or stream
+func (c *Client) WithContext(ctx context.Context) *Client {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+// Alignment events discarded so far because the alignment channel was not drained
+func (c *Client) DroppedAlignments() uint64 {
+	return c.dropped.Load()
+}
+
+// Client used by the package level REST helpers
+func defaultClient(apiKey string) *Client {
+	return NewClient(context.Background(), apiKey, 1*time.Second)
 }
 
 func GetUserCapacity(apiKey string) (*UserAndCapacity, error) {
-	u, err := User(apiKey)
+	return defaultClient(apiKey).GetUserCapacity()
+}
+
+func (c *Client) GetUserCapacity() (*UserAndCapacity, error) {
+	u, err := c.User()
 	if err != nil {
 		return nil, err
 	}
@@ -106,118 +144,41 @@ func GetUserCapacity(apiKey string) (*UserAndCapacity, error) {
 
 // API Get user
 func User(apiKey string) (*UserData, error) {
-	url := fmt.Sprintf("%s/user", ELEVEN_BASEURL_HTTPS)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-	req.Header.Set("xi-api-key", apiKey)
-
-	client := &http.Client{
-		Timeout: 1 * time.Second,
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+	return defaultClient(apiKey).User()
+}
 
+func (c *Client) User() (*UserData, error) {
 	var r UserData
-	err = json.Unmarshal(body, &r)
-	if err != nil {
+	if err := c.doJSON(http.MethodGet, "/user", nil, &r); err != nil {
 		return nil, err
 	}
-
 	return &r, nil
 }
 
 func GetVoice(apiKey string, voiceId string) (*GetVoiceVoice, error) {
-	url := fmt.Sprintf("%s/voices/%s", ELEVEN_BASEURL_HTTPS, voiceId)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-	req.Header.Set("xi-api-key", apiKey)
-
-	client := &http.Client{
-		Timeout: 1 * time.Second,
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+	return defaultClient(apiKey).GetVoice(voiceId)
+}
 
+func (c *Client) GetVoice(voiceId string) (*GetVoiceVoice, error) {
 	var r GetVoiceVoice
-	err = json.Unmarshal(body, &r)
-	if err != nil {
+	if err := c.doJSON(http.MethodGet, "/voices/"+neturl.PathEscape(voiceId), nil, &r); err != nil {
 		return nil, err
 	}
-
 	return &r, nil
 }
 
 func SharedVoices(apiKey string, params ListVoicesParams) (*ListVoicesResponse, error) {
-	url := fmt.Sprintf("%s/shared-voices", ELEVEN_BASEURL_HTTPS)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-	req.Header.Set("xi-api-key", apiKey)
-
-	client := &http.Client{
-		Timeout: 1 * time.Second,
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	var r ListVoicesResponse
-	err = json.Unmarshal(body, &r)
-	if err != nil {
-		return nil, err
-	}
-
-	return &r, nil
-	//https://api.elevenlabs.io/v1/shared-voices
+	return defaultClient(apiKey).SharedVoices(params)
 }
 
 func ValidateLanguageAndModel(apiKey string, voiceId string, modelName string) (bool, error) {
-	gv, err := GetVoice(apiKey, voiceId) // Voice exists?
+	return defaultClient(apiKey).ValidateLanguageAndModel(voiceId, modelName)
+}
+
+func (c *Client) ValidateLanguageAndModel(voiceId string, modelName string) (bool, error) {
+	gv, err := c.GetVoice(voiceId) // Voice exists?
 	if err != nil {
-		return false, fmt.Errorf("voice lookup failed")
+		return false, fmt.Errorf("voice lookup failed: %w", err)
 	}
 	if gv.VoiceID == "" {
 		return false, fmt.Errorf("voice not found")
@@ -237,44 +198,18 @@ func ValidateLanguageAndModel(apiKey string, voiceId string, modelName string) (
 	return true, nil
 }
 
-func debug(prefix string, args ...any) {
-	parts := []string{"🎲🎲🎲 " + prefix}
-
-	for _, arg := range args {
-		switch v := arg.(type) {
-		case string:
-			parts = append(parts, v)
-		default:
-			b, err := json.MarshalIndent(v, "", "  ")
-			if err != nil {
-				parts = append(parts, fmt.Sprintf("❌ json error: %v", err))
-			} else {
-				parts = append(parts, string(b))
-			}
-		}
-	}
-	fmt.Println(strings.Join(parts, " "))
-}
-
-// Standard Websocket Request
-func (c *Client) StreamingRequest(TextReader chan string, AlignmentResponseChannel chan StreamingOutputResponse, AudioResponsePipe io.Writer, voiceID string, modelID string, req TextToSpeechInputStreamingRequest, queries ...QueryFunc) error {
-	driverActive := true // Driver shut down?
-	driverError := false // Unexpected errors
-
-	// url := fmt.Sprintf("%s/text-to-speech/%s/stream-input?model_id=%s", ELEVEN_BASEURL_WSS, voiceID, modelID)
-	url := fmt.Sprintf("%s/text-to-speech/%s/multi-stream-input?model_id=%s&inactivity_timeout=180&sync_alignment=true", ELEVEN_BASEURL_WSS, voiceID, modelID)
-	multiCtx := cuid2.Generate()
-
+// Dial a TTS websocket endpoint
+func dialStream(ctx context.Context, dialer *websocket.Dialer, apiKey string, url string, queries ...QueryFunc) (*websocket.Conn, error) {
 	headers := http.Header{}
 	headers.Add("Accept", "*/*")
 	headers.Add("Content-Type", JSON_CONTENT_TYPE)
-	if c.apiKey != "" {
-		headers.Add("xi-api-key", c.apiKey)
+	if apiKey != "" {
+		headers.Add("xi-api-key", apiKey)
 	}
 
 	u, err := neturl.Parse(url)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	q := u.Query()
@@ -283,149 +218,60 @@ func (c *Client) StreamingRequest(TextReader chan string, AlignmentResponseChann
 	}
 	u.RawQuery = q.Encode()
 
-	conn, _, err := websocket.DefaultDialer.DialContext(c.ctx, u.String(), headers)
+	conn, _, err := dialer.DialContext(ctx, u.String(), headers)
 	if err != nil {
-		return err
-	}
-	defer conn.Close()
-
-	debug("Connected to Eleven Labs TTS WebSocket")
-
-	initReq := TextToSpeechInputMultiStreamingRequest{
-		Text:          " ",
-		ContextID:     multiCtx,
-		VoiceSettings: req.VoiceSettings,
+		return nil, err
 	}
+	return conn, nil
+}
 
-	debug("Sending initialization request", initReq)
+// Standard Websocket Request, TextReader may carry CLOSURE_MARKER and FLUSH_MARKER
+func (c *Client) StreamingRequest(TextReader chan string, AlignmentResponseChannel chan StreamingOutputResponse, AudioResponsePipe io.Writer, voiceID string, modelID string, req TextToSpeechInputStreamingRequest, queries ...QueryFunc) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	return c.StreamingRequestEvents(markerEvents(TextReader, stop), AlignmentResponseChannel, AudioResponsePipe, voiceID, modelID, req, queries...)
+}
 
-	// Send initial request
-	if err := conn.WriteJSON(initReq); err != nil {
+// Standard Websocket Request driven by typed input events
+func (c *Client) StreamingRequestEvents(InputReader <-chan InputEvent, AlignmentResponseChannel chan StreamingOutputResponse, AudioResponsePipe io.Writer, voiceID string, modelID string, req TextToSpeechInputStreamingRequest, queries ...QueryFunc) error {
+	// url := fmt.Sprintf("%s/text-to-speech/%s/stream-input?model_id=%s", ELEVEN_BASEURL_WSS, voiceID, modelID)
+	url := fmt.Sprintf("%s/text-to-speech/%s/multi-stream-input?model_id=%s&inactivity_timeout=180&sync_alignment=true", c.opts.baseURLWSS, voiceID, modelID)
+	tenant := c.opts.tenantFor(c.apiKey)
+	if err := c.opts.quota.Check(tenant); err != nil {
 		return err
 	}
-
-	// Input watcher
-	inputCtx, inputCancel := context.WithCancel(context.Background())
-
-	errCh := make(chan error, 1)
-	var wg sync.WaitGroup
-	wg.Add(1)
-
-	// Response watching
-	go func(wg *sync.WaitGroup, errCh chan<- error) {
-		defer wg.Done()
-		for {
-			select {
-			case <-c.ctx.Done():
-				return
-			default:
-				if !driverActive {
-					return
-				}
-				var input StreamingInputResponse
-				var response StreamingOutputResponse
-				if err := conn.ReadJSON(&input); err != nil {
-					if driverActive {
-						errCh <- err
-						driverError = true
-						inputCancel()
-					}
-					return
-				}
-
-				b, err := base64.StdEncoding.DecodeString(input.Audio)
-				if err != nil {
-					if driverActive {
-						errCh <- err
-						driverError = true
-						inputCancel()
-					}
-					return
-				}
-				// Send audio through the pipeline
-				if _, err := AudioResponsePipe.Write(b); err != nil {
-					break
-				}
-
-				// Send non-audio via the response channel
-				response = StreamingOutputResponse{
-					IsFinal:             input.IsFinal,
-					NormalizedAlignment: input.NormalizedAlignment,
-					Alignment:           input.Alignment,
-				}
-				AlignmentResponseChannel <- response
-			}
-		}
-	}(&wg, errCh)
-
-	// Input watching
-InputWatcher:
-	for {
-		select {
-		case <-inputCtx.Done():
-			driverActive = false
-			break InputWatcher
-		case <-c.ctx.Done():
-			driverActive = false
-			break InputWatcher
-		case chunk, ok := <-TextReader:
-			if !ok || !driverActive {
-				break InputWatcher
-			}
-			final := false
-			var ch *TextToSpeechInputMultiStreamingRequest
-			switch {
-			case chunk == CLOSURE_MARKER:
-				final = true
-				ch = &TextToSpeechInputMultiStreamingRequest{Flush: true, ContextID: multiCtx}
-				debug("Sending context closure", ch)
-			case chunk == FLUSH_MARKER:
-				ch = &TextToSpeechInputMultiStreamingRequest{Flush: true, ContextID: multiCtx}
-				debug("Sending flush", ch)
-			default:
-				ch = &TextToSpeechInputMultiStreamingRequest{Text: chunk, ContextID: multiCtx}
-				debug("Sending chunk", ch)
-			}
-			if err := conn.WriteJSON(ch); err != nil {
-				errCh <- err
-				break InputWatcher
-			}
-			if final {
-				ch = &TextToSpeechInputMultiStreamingRequest{CloseSocket: true}
-				debug("Sending socket closure", ch)
-				if err := conn.WriteJSON(ch); err != nil {
-					errCh <- err
-					break InputWatcher
-				}
-			}
-
-		}
-	}
-
-	// Send final "" to close out TTS buffer
-	if driverActive && !driverError {
-		if err := conn.WriteJSON(map[string]string{"text": ""}); err != nil {
-			if c.ctx.Err() == nil {
-				errCh <- err
-			}
-		}
-	}
-	conn.Close()
-	wg.Wait()
-
-	// Errors?
-	select {
-	case readErr := <-errCh:
-		if driverActive || driverError {
-			// Only send if the driver is active or the unexpected error flag is active
-			return readErr
-		} else {
-			return nil
-		}
-	default:
+	multiCtx := cuid2.Generate()
+	init, err := c.opts.initFrame(TextToSpeechInputMultiStreamingRequest{
+		Text:                            " ",
+		ContextID:                       multiCtx,
+		VoiceSettings:                   req.VoiceSettings,
+		PronunciationDictionaryLocators: req.PronunciationDictionaryLocators,
+	})
+	if err != nil {
+		return err
 	}
-
-	return nil
+	alignment := newAlignmentQueue(AlignmentResponseChannel, c.opts.delivery, mergeStreamingOutput, c.dropped)
+	defer alignment.discard()
+
+	d := &streamDriver{
+		ctx:        c.ctx,
+		apiKey:     c.apiKey,
+		timeout:    c.timeout,
+		opts:       &c.opts,
+		log:        c.opts.log().With("voice_id", voiceID, "model_id", modelID, "context_id", multiCtx),
+		tenant:     tenant,
+		url:        url,
+		queries:    queries,
+		initFrames: []TextToSpeechInputMultiStreamingRequest{init},
+		contextID:  multiCtx,
+		input:      InputReader,
+		voice:      chunkSettings{voice: req.VoiceSettings},
+		audio:      AudioResponsePipe,
+		deliver: func(response StreamingOutputResponse, stop <-chan struct{}) bool {
+			return alignment.push(c.ctx, stop, response, response.IsFinal)
+		},
+	}
+	return d.run()
 }
 
 func (c *MultiClient) activeContextCount() int {
@@ -441,182 +287,134 @@ func (c *MultiClient) hasMultiCtx(id string) bool {
 	return ok
 }
 
-func (c *MultiClient) addMultiCtx(id string) {
+// Open context, ids reserved by an OpenContextWait still waiting for admission are not
+func (c *MultiClient) getMultiCtx(id string) *multiContext {
+	c.cmu.RLock()
+	mc := c.activeRequests[id]
+	c.cmu.RUnlock()
+	if mc != nil && mc.opening.Load() {
+		return nil
+	}
+	return mc
+}
+
+func (c *MultiClient) addMultiCtx(mc *multiContext) {
 	c.cmu.Lock()
-	c.activeRequests[id] = struct{}{}
+	c.activeRequests[mc.id] = mc
 	c.cmu.Unlock()
 }
 
-func (c *MultiClient) removeMultiCtx(id string) {
+// Register mc unless its id is taken, checked and inserted in one step so concurrent opens cannot both win
+func (c *MultiClient) reserveMultiCtx(mc *multiContext) error {
 	c.cmu.Lock()
-	delete(c.activeRequests, id)
-	c.cmu.Unlock()
+	defer c.cmu.Unlock()
+	if other := c.activeRequests[mc.id]; other != nil {
+		if other.closing.Load() {
+			return fmt.Errorf("context %s is still closing", mc.id)
+		}
+		return fmt.Errorf("context %s already open", mc.id)
+	}
+	c.activeRequests[mc.id] = mc
+	return nil
 }
 
-func (c *MultiClient) HasCapacity() bool {
+// Whether mc is still registered, false once the session dropped it
+func (c *MultiClient) ownsMultiCtx(mc *multiContext) bool {
 	c.cmu.RLock()
 	defer c.cmu.RUnlock()
-	return len(c.activeRequests) < MULTI_CONTEXT_MAX_REQUESTS
+	return c.activeRequests[mc.id] == mc
 }
 
-func (c *MultiClient) MultiCtxStreamingRequest(TextReader chan string, AlignmentResponseChannel chan StreamingOutputMultiCtxResponse, AudioResponsePipe io.Writer, voiceID string, modelID string, queries ...QueryFunc) error {
-	driverActive := true // Driver shut down?
-	driverError := false // Unexpected errors
-
-	// // Eval context and capacity
-	// if !c.hasMultiCtx(req.ContextID) {
-	// 	if c.activeContextCount() >= MULTI_CONTEXT_MAX_REQUESTS {
-	// 		return fmt.Errorf("active requests reached: %d", MULTI_CONTEXT_MAX_REQUESTS)
-	// 	}
-	// 	c.addMultiCtx(req.ContextID)
-	// }
-
-	// Make request
-	url := fmt.Sprintf("%s/text-to-speech/%s/multi-stream-input?model_id=%s&inactivity_timeout=180&sync_alignment=true", ELEVEN_BASEURL_WSS, voiceID, modelID)
-	headers := http.Header{}
-	headers.Add("Accept", "*/*")
-	headers.Add("Content-Type", JSON_CONTENT_TYPE)
-	if c.apiKey != "" {
-		headers.Add("xi-api-key", c.apiKey)
+// Remove a reservation that failed to open, unless the session dropped it and the id was taken again
+func (c *MultiClient) unreserveMultiCtx(mc *multiContext) {
+	c.cmu.Lock()
+	if c.activeRequests[mc.id] == mc {
+		delete(c.activeRequests, mc.id)
 	}
+	c.cmu.Unlock()
+	c.releaseSlot(mc)
+}
 
-	u, err := neturl.Parse(url)
-	if err != nil {
-		return err
+func (c *MultiClient) removeMultiCtx(id string) {
+	c.cmu.Lock()
+	mc := c.activeRequests[id]
+	delete(c.activeRequests, id)
+	c.cmu.Unlock()
+	if mc != nil {
+		c.releaseSlot(mc)
+		if mc.queue != nil {
+			mc.queue.discard() // Coalesced events that will never be delivered
+		}
 	}
+}
 
-	q := u.Query()
-	for _, qf := range queries {
-		qf(&q)
-	}
-	u.RawQuery = q.Encode()
+func (c *MultiClient) HasCapacity() bool {
+	return len(c.slots) < cap(c.slots)
+}
 
-	conn, _, err := websocket.DefaultDialer.DialContext(c.ctx, u.String(), headers)
-	if err != nil {
-		return err
-	}
-	defer conn.Close()
+// Multi-context request on its own socket, TextReader may carry CLOSURE_MARKER and FLUSH_MARKER
+func (c *MultiClient) MultiCtxStreamingRequest(TextReader chan string, AlignmentResponseChannel chan StreamingOutputMultiCtxResponse, AudioResponsePipe io.Writer, voiceID string, modelID string, queries ...QueryFunc) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	return c.MultiCtxStreamingRequestEvents(markerEvents(TextReader, stop), AlignmentResponseChannel, AudioResponsePipe, voiceID, modelID, queries...)
+}
 
-	// Send initialization request and close initialization context
-	var initReq TextToSpeechInputMultiStreamingRequest
-	initCtx := cuid2.Generate()
-	initReq = TextToSpeechInputMultiStreamingRequest{
-		Text:      " ",
-		ContextID: initCtx,
-	}
-	if err := conn.WriteJSON(initReq); err != nil {
+// Multi-context request driven by typed input events
+func (c *MultiClient) MultiCtxStreamingRequestEvents(InputReader <-chan InputEvent, AlignmentResponseChannel chan StreamingOutputMultiCtxResponse, AudioResponsePipe io.Writer, voiceID string, modelID string, queries ...QueryFunc) error {
+	// Eval budget, context and capacity, initialization context is closed straight away
+	tenant := c.opts.tenantFor(c.apiKey)
+	if err := c.opts.quota.Check(tenant); err != nil {
 		return err
 	}
-	initReq = TextToSpeechInputMultiStreamingRequest{
-		CloseContext: true,
-		ContextID:    initCtx,
+	multiCtx := cuid2.Generate()
+	initCtx := cuid2.Generate()
+	initFrames := []TextToSpeechInputMultiStreamingRequest{
+		{Text: " ", ContextID: initCtx},
+		{CloseContext: true, ContextID: initCtx},
+	}
+	if len(c.opts.dictionaries) > 0 {
+		init, err := c.opts.initFrame(TextToSpeechInputMultiStreamingRequest{Text: " ", ContextID: multiCtx})
+		if err != nil {
+			return err
+		}
+		initFrames = append(initFrames, init)
 	}
-	if err := conn.WriteJSON(initReq); err != nil {
+	if err := c.acquireSlot(c.ctx); err != nil {
 		return err
 	}
+	c.addMultiCtx(&multiContext{id: multiCtx})
+	defer c.removeMultiCtx(multiCtx)
 
-	// Input watcher
-	inputCtx, inputCancel := context.WithCancel(context.Background())
-
-	errCh := make(chan error, 1)
-	var wg sync.WaitGroup
-	wg.Add(1)
-
-	// Response watching
-	go func(wg *sync.WaitGroup, errCh chan<- error) {
-		defer wg.Done()
-		for {
-			select {
-			case <-c.ctx.Done():
-				return
-			default:
-				if !driverActive {
-					return
-				}
-				var input StreamingInputResponse
-				var response StreamingOutputMultiCtxResponse
-				if err := conn.ReadJSON(&input); err != nil {
-					if driverActive {
-						errCh <- err
-						driverError = true
-						inputCancel()
-					}
-					return
-				}
-
-				b, err := base64.StdEncoding.DecodeString(input.Audio)
-				if err != nil {
-					if driverActive {
-						errCh <- err
-						driverError = true
-						inputCancel()
-					}
-					return
-				}
-				// Send audio through the pipeline
-				if _, err := AudioResponsePipe.Write(b); err != nil {
-					break
-				}
-
-				// Send non-audio via the response channel
-				response = StreamingOutputMultiCtxResponse{
-					IsFinal:             input.IsFinal,
-					NormalizedAlignment: input.NormalizedAlignment,
-					Alignment:           input.Alignment,
-				}
-				AlignmentResponseChannel <- response
-			}
-		}
-	}(&wg, errCh)
-
-	// Input watching
-InputWatcher:
-	for {
-		select {
-		case <-inputCtx.Done():
-			driverActive = false
-			break InputWatcher
-		case <-c.ctx.Done():
-			driverActive = false
-			break InputWatcher
-		case chunk, ok := <-TextReader:
-			if !ok || !driverActive {
-				break InputWatcher
-			}
-			ch := &TextToSpeechInputStreamingRequest{Text: chunk, ContextID: ""}
-			if err := conn.WriteJSON(ch); err != nil {
-				errCh <- err
-				break InputWatcher
-			}
-		}
-	}
-
-	// Send final "" to close out TTS buffer
-	if driverActive && !driverError {
-		if err := conn.WriteJSON(map[string]string{"text": ""}); err != nil {
-			if c.ctx.Err() == nil {
-				errCh <- err
+	// Make request
+	url := fmt.Sprintf("%s/text-to-speech/%s/multi-stream-input?model_id=%s&inactivity_timeout=180&sync_alignment=true", c.opts.baseURLWSS, voiceID, modelID)
+	alignment := newAlignmentQueue(AlignmentResponseChannel, c.opts.delivery, mergeMultiCtxOutput, &c.dropped)
+	defer alignment.discard()
+
+	d := &streamDriver{
+		ctx:        c.ctx,
+		apiKey:     c.apiKey,
+		timeout:    c.timeout,
+		opts:       &c.opts,
+		log:        c.opts.log().With("voice_id", voiceID, "model_id", modelID, "context_id", multiCtx),
+		tenant:     tenant,
+		url:        url,
+		queries:    queries,
+		initFrames: initFrames,
+		contextID:  multiCtx,
+		input:      InputReader,
+		audio:      AudioResponsePipe,
+		deliver: func(resp StreamingOutputResponse, stop <-chan struct{}) bool {
+			response := StreamingOutputMultiCtxResponse{
+				IsFinal:             resp.IsFinal,
+				NormalizedAlignment: resp.NormalizedAlignment,
+				Alignment:           resp.Alignment,
+				AudioDurationMs:     resp.AudioDurationMs,
+				ContextId:           multiCtx,
 			}
-		}
-	}
-	conn.Close()
-	wg.Wait()
-
-	// Errors?
-	select {
-	case readErr := <-errCh:
-		//c.removeMultiCtx(req.ContextID)
-		if driverActive || driverError {
-			// Only send if the driver is active or the unexpected error flag is active
-			return readErr
-		} else {
-			return nil
-		}
-	default:
+			return alignment.push(c.ctx, stop, response, resp.IsFinal)
+		},
 	}
-	//c.removeMultiCtx(req.ContextID)
-
-	return nil
+	return d.run()
 }
 
 func LanguageCode(value string) QueryFunc {
@@ -3,36 +3,585 @@ package elevenlabs
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nrednav/cuid2"
 )
 
+var ErrSessionClosed = errors.New("multi-context session closed")
+var ErrUnknownContext = errors.New("unknown context")
+var ErrContextCapacity = fmt.Errorf("active requests reached: %d", MULTI_CONTEXT_MAX_REQUESTS)
+
 type MultiClient struct {
-	apiKey                   string
-	timeout                  time.Duration
-	ctx                      context.Context
-	activeRequests           map[string]struct{} // Active multi-context requests
-	cmu                      sync.RWMutex
-	TextReader               chan string
-	AlignmentResponseChannel chan StreamingOutputMultiCtxResponse
+	apiKey         string
+	timeout        time.Duration
+	ctx            context.Context
+	opts           options
+	activeRequests map[string]*multiContext // Active multi-context requests
+	cmu            sync.RWMutex
+	slots          chan struct{} // Admission semaphore, one entry per open context
+	dropped        atomic.Uint64 // Alignment events discarded by the delivery policy
+
+	// Session state, set by Connect
+	log     *slog.Logger
+	format  AudioFormatInfo
+	conn    *websocket.Conn
+	wmu     sync.Mutex // Serializes socket writes
+	done    chan struct{}
+	stop    chan struct{} // Closed by Close to unblock alignment delivery
+	closing atomic.Bool
+	err     error
 }
 
-// Multi-Context Websocket Session
-func NewMultiContextSession(ctx context.Context, apiKey string, reqTimeout time.Duration, TextReader chan string, AlignmentResponseChannel chan StreamingOutputMultiCtxResponse, AudioResponsePipe io.Writer, voiceID string, modelID string, req TextToSpeechInputMultiStreamingRequest, queries ...QueryFunc) *MultiClient {
+// Per-context destination for audio and alignment
+type ContextSink struct {
+	Audio     io.Writer
+	Alignment chan StreamingOutputMultiCtxResponse
+	OnError   func(error) // Called when the context fails, e.g. with an *APIError
+}
+
+type multiContext struct {
+	id       string
+	sink     ContextSink
+	queue    *alignmentQueue[StreamingOutputMultiCtxResponse]
+	session  bool        // Routed by the session socket
+	closing  atomic.Bool // close_context sent, draining until isFinal
+	released atomic.Bool // Admission slot returned, or not taken yet
+	opening  atomic.Bool // Id reserved, waiting for admission and the init frame
+
+	vmu   sync.Mutex
+	voice chunkSettings // Voice settings for SendTextWith overrides
+
+	// Playback accounting for Interrupt, guarded by mu
+	mu          sync.Mutex
+	interrupted bool
+	audioBytes  int
+	aligned     *AlignmentAggregator
+	marked      bool // No audio written since the last boundary
+
+	// Flush boundaries, guarded by mu
+	sentChars  int   // Characters of text sent
+	ackedChars int   // Characters aligned by the server
+	flushes    []int // sentChars at each pending flush
+}
+
+// What an interrupted context had delivered before the cut
+type InterruptResult struct {
+	ContextID string
+	PlayedMs  int        // Audio delivered to the sink, capped by InterruptAt
+	Chars     int        // Characters of the original text whose audio was played
+	Text      string     // Those characters
+	Words     []WordSpan // Words that were played completely
+}
 
+// Multi-context client, sessions are opened with Connect
+func NewMultiClient(ctx context.Context, apiKey string, reqTimeout time.Duration, opts ...Option) *MultiClient {
 	return &MultiClient{
-		apiKey:                   apiKey,
-		timeout:                  reqTimeout,
-		ctx:                      ctx,
-		activeRequests:           make(map[string]struct{}),
-		TextReader:               TextReader,
-		AlignmentResponseChannel: AlignmentResponseChannel,
-	}
-
-	// err := c.MultiCtxStreamingRequest(TextReader, AlignmentResponseChannel, AudioResponsePipe, voiceID, modelID, queries...)
-	// if err != nil {
-	// 	return err
-	// }
-	// return nil
+		apiKey:         apiKey,
+		timeout:        reqTimeout,
+		ctx:            ctx,
+		opts:           newOptions(opts),
+		activeRequests: make(map[string]*multiContext),
+		slots:          make(chan struct{}, MULTI_CONTEXT_MAX_REQUESTS),
+	}
+}
+
+// Multi-Context Websocket Session
+func NewMultiContextSession(ctx context.Context, apiKey string, reqTimeout time.Duration, voiceID string, modelID string, queries []QueryFunc, opts ...Option) (*MultiClient, error) {
+	c := NewMultiClient(ctx, apiKey, reqTimeout, opts...)
+	if err := c.Connect(voiceID, modelID, queries...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Open the session socket, contexts are then managed with OpenContext/CloseContext
+func (c *MultiClient) Connect(voiceID string, modelID string, queries ...QueryFunc) error {
+	if c.conn != nil {
+		return fmt.Errorf("session already connected")
+	}
+	if err := c.opts.quota.Check(c.opts.tenantFor(c.apiKey)); err != nil {
+		return err
+	}
+
+	log := c.opts.log().With("voice_id", voiceID, "model_id", modelID)
+	start := time.Now()
+	url := fmt.Sprintf("%s/text-to-speech/%s/multi-stream-input?model_id=%s&inactivity_timeout=180&sync_alignment=true", c.opts.baseURLWSS, voiceID, modelID)
+	conn, err := dialStream(c.ctx, c.opts.wsDialer(c.timeout), c.apiKey, url, queries...)
+	if err != nil {
+		log.Error("session connect failed", "error", err)
+		return err
+	}
+	log.Info("session connected", "latency", time.Since(start))
+
+	// Initialize the socket with a throwaway context
+	initCtx := cuid2.Generate()
+	for _, initReq := range []TextToSpeechInputMultiStreamingRequest{
+		{Text: " ", ContextID: initCtx},
+		{CloseContext: true, ContextID: initCtx},
+	} {
+		if err := conn.WriteJSON(initReq); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	c.log = log
+	c.format, _ = ResolveAudioFormat(queries...)
+	c.conn = conn
+	c.done = make(chan struct{})
+	c.stop = make(chan struct{})
+	go c.readLoop()
+
+	return nil
+}
+
+// Start a new context on the session, audio and alignment for it are routed to sink
+func (c *MultiClient) OpenContext(id string, voiceSettings *VoiceSettings, sink ContextSink, ctxFuncs ...ContextFunc) error {
+	return c.OpenContextWait(c.ctx, id, voiceSettings, sink, ctxFuncs...)
+}
+
+// OpenContext with admission bounded by ctx when the client uses AdmissionWait
+func (c *MultiClient) OpenContextWait(ctx context.Context, id string, voiceSettings *VoiceSettings, sink ContextSink, ctxFuncs ...ContextFunc) error {
+	if id == "" {
+		return fmt.Errorf("context id required")
+	}
+	if c.conn == nil {
+		return fmt.Errorf("session not connected")
+	}
+	if err := c.opts.quota.Check(c.opts.tenantFor(c.apiKey)); err != nil {
+		return err
+	}
+	init, err := c.opts.initFrame(TextToSpeechInputMultiStreamingRequest{
+		Text:          " ",
+		ContextID:     id,
+		VoiceSettings: voiceSettings,
+	}, ctxFuncs...)
+	if err != nil {
+		return err
+	}
+
+	// The id is taken before waiting, a concurrent open of the same id fails instead of racing for it
+	mc := &multiContext{
+		id:      id,
+		sink:    sink,
+		queue:   newAlignmentQueue(sink.Alignment, c.opts.delivery, mergeMultiCtxOutput, &c.dropped),
+		session: true,
+		aligned: NewAlignmentAggregator(),
+		voice:   chunkSettings{voice: voiceSettings},
+	}
+	mc.released.Store(true)
+	mc.opening.Store(true)
+	if err := c.reserveMultiCtx(mc); err != nil {
+		return err
+	}
+	if err := c.acquireSlot(ctx); err != nil {
+		c.unreserveMultiCtx(mc)
+		return err
+	}
+	mc.released.Store(false)
+	if !c.ownsMultiCtx(mc) {
+		c.releaseSlot(mc) // Dropped with the session while waiting
+		return ErrSessionClosed
+	}
+
+	if c.format.Format != "" {
+		announceFormat(sink.Audio, c.format, nil)
+	}
+	if err := c.write(init); err != nil {
+		c.unreserveMultiCtx(mc)
+		return err
+	}
+	mc.opening.Store(false)
+	c.log.Debug("context opened", "context_id", id)
+	return nil
+}
+
+// Send a chunk of text to an open context
+func (c *MultiClient) SendText(id string, text string) error {
+	return c.SendTextWith(id, text, nil)
+}
+
+// Send a chunk of text with voice and generation overrides, voice changes stay in effect for later chunks
+func (c *MultiClient) SendTextWith(id string, text string, overrides *ChunkOverrides) error {
+	mc, err := c.writableCtx(id)
+	if err != nil {
+		return err
+	}
+	req := TextToSpeechInputMultiStreamingRequest{Text: text, ContextID: id}
+
+	// Held across the write so settings reach the socket in order
+	mc.vmu.Lock()
+	defer mc.vmu.Unlock()
+	mc.voice.apply(&req, overrides)
+	c.log.Debug("sending chunk", "context_id", id, c.opts.textAttr(text), "voice_settings", req.VoiceSettings != nil)
+	if err := c.write(req); err != nil {
+		return err
+	}
+	chars := billedChars(text)
+	c.opts.quota.Add(c.opts.tenantFor(c.apiKey), chars)
+	mc.mu.Lock()
+	mc.sentChars += chars
+	mc.mu.Unlock()
+	return nil
+}
+
+// Force generation of any text buffered for the context, the sink gets a boundary once its audio is written
+func (c *MultiClient) Flush(id string) error {
+	mc, err := c.writableCtx(id)
+	if err != nil {
+		return err
+	}
+	if err := c.write(TextToSpeechInputMultiStreamingRequest{Flush: true, ContextID: id}); err != nil {
+		return err
+	}
+	mc.mu.Lock()
+	if n := len(mc.flushes); n == 0 || mc.flushes[n-1] < mc.sentChars {
+		mc.flushes = append(mc.flushes, mc.sentChars)
+	}
+	mc.mu.Unlock()
+	return nil
+}
+
+// Close a context, audio already generated keeps flowing until the server sends isFinal
+func (c *MultiClient) CloseContext(id string) error {
+	mc := c.getMultiCtx(id)
+	if mc == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownContext, id)
+	}
+	if mc.closing.Swap(true) {
+		return nil
+	}
+	if err := c.write(TextToSpeechInputMultiStreamingRequest{CloseContext: true, ContextID: id}); err != nil {
+		c.removeMultiCtx(id)
+		return err
+	}
+	// The server frees the context on close, only routing waits for isFinal
+	c.releaseSlot(mc)
+	c.log.Debug("context closing", "context_id", id)
+	return nil
+}
+
+// Stop a context mid-speech: close it, discard audio still in flight and free its slot.
+// The result reports how much of it reached the sink, a new context can start right away.
+// The id stays reserved until the server confirms the closure, so late audio is never misrouted.
+func (c *MultiClient) Interrupt(id string) (InterruptResult, error) {
+	return c.InterruptAt(id, -1)
+}
+
+// Interrupt with the playback position known to the caller, e.g. behind a jitter buffer
+func (c *MultiClient) InterruptAt(id string, played time.Duration) (InterruptResult, error) {
+	mc := c.getMultiCtx(id)
+	if mc == nil || !mc.session {
+		return InterruptResult{}, fmt.Errorf("%w: %s", ErrUnknownContext, id)
+	}
+
+	// Waits for an audio write in progress, nothing reaches the sink afterwards
+	mc.mu.Lock()
+	mc.interrupted = true
+	playedMs := mc.aligned.DurationMs()
+	if d := c.format.Duration(mc.audioBytes); d > 0 {
+		playedMs = int(d.Milliseconds())
+	}
+	if played >= 0 {
+		playedMs = min(playedMs, int(played.Milliseconds()))
+	}
+	result := InterruptResult{
+		ContextID: id,
+		PlayedMs:  playedMs,
+		Words:     mc.aligned.SpokenWords(playedMs),
+	}
+	alignment := mc.aligned.Alignment()
+	mc.boundary()
+	mc.mu.Unlock()
+
+	for i, start := range alignment.CharStartTimesMs {
+		if start+alignment.CharDurationsMs[i] > playedMs {
+			break
+		}
+		result.Chars++
+		result.Text += alignment.Chars[i]
+	}
+
+	// Routing drops its frames from here on and removes it on isFinal
+	c.releaseSlot(mc)
+	c.log.Info("context interrupted", "context_id", id, "played_ms", result.PlayedMs, "chars", result.Chars)
+
+	if mc.closing.Swap(true) {
+		return result, nil
+	}
+	if err := c.write(TextToSpeechInputMultiStreamingRequest{CloseContext: true, ContextID: id}); err != nil {
+		c.removeMultiCtx(id)
+		return result, err
+	}
+	return result, nil
+}
+
+// Close the session socket, waiting up to the request timeout for the server to finish.
+// Without a timeout it waits until the server closes the socket or the context is done.
+func (c *MultiClient) Close() error {
+	if c.conn == nil || c.closing.Swap(true) {
+		return nil
+	}
+
+	c.write(TextToSpeechInputMultiStreamingRequest{CloseSocket: true})
+	var timeout <-chan time.Time
+	if c.timeout > 0 {
+		timer := time.NewTimer(c.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-c.done:
+	case <-timeout:
+	case <-c.ctx.Done():
+	}
+	close(c.stop)
+	c.conn.Close()
+	<-c.done
+
+	return c.Err()
+}
+
+// Audio format of the session, zero when output_format is not recognized
+func (c *MultiClient) Format() AudioFormatInfo {
+	return c.format
+}
+
+// Alignment events discarded so far because a context's alignment channel was not drained
+func (c *MultiClient) DroppedAlignments() uint64 {
+	return c.dropped.Load()
+}
+
+// Closed once the session socket has shut down
+func (c *MultiClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// Error that terminated the session, nil after a clean Close
+func (c *MultiClient) Err() error {
+	c.cmu.RLock()
+	defer c.cmu.RUnlock()
+	return c.err
+}
+
+func (c *MultiClient) writableCtx(id string) (*multiContext, error) {
+	mc := c.getMultiCtx(id)
+	if mc == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownContext, id)
+	}
+	if mc.closing.Load() {
+		return nil, fmt.Errorf("context %s is closing", id)
+	}
+	return mc, nil
+}
+
+func (c *MultiClient) write(req TextToSpeechInputMultiStreamingRequest) error {
+	if c.conn == nil {
+		return fmt.Errorf("session not connected")
+	}
+	select {
+	case <-c.done:
+		return ErrSessionClosed
+	default:
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.timeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	return c.conn.WriteJSON(req)
+}
+
+// Reserve an admission slot according to the configured AdmissionMode
+func (c *MultiClient) acquireSlot(ctx context.Context) error {
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if c.opts.admission == AdmissionFailFast {
+		return ErrContextCapacity
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-c.done:
+		return ErrSessionClosed
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %w", ErrContextCapacity, ctx.Err())
+	}
+}
+
+func (c *MultiClient) releaseSlot(mc *multiContext) {
+	if !mc.released.Swap(true) {
+		<-c.slots
+	}
+}
+
+// Session response watching, routes frames to their context sink
+func (c *MultiClient) readLoop() {
+	defer close(c.done)
+	defer c.dropSessionContexts()
+
+	stop := context.AfterFunc(c.ctx, func() { c.conn.Close() })
+	defer stop()
+
+	for {
+		var input StreamingOutputMultiCtxRawResponse
+		if err := c.conn.ReadJSON(&input); err != nil {
+			if !c.closing.Load() && c.ctx.Err() == nil {
+				c.fail(classifyError(err))
+			}
+			c.log.Info("session closed")
+			return
+		}
+
+		apiErr := frameError(input.Error, input.Message)
+		if apiErr != nil && input.contextID() == "" {
+			c.fail(apiErr)
+			c.conn.Close()
+			continue // Surfaces as a read error
+		}
+
+		mc := c.getMultiCtx(input.contextID())
+		if mc == nil {
+			continue // Closed or initialization context
+		}
+		if apiErr != nil {
+			c.log.Warn("context failed", "context_id", mc.id, "error", apiErr)
+			c.removeMultiCtx(mc.id)
+			if mc.sink.OnError != nil {
+				mc.sink.OnError(apiErr)
+			}
+			continue
+		}
+
+		audioMs, ok := c.routeAudio(mc, &input)
+		if !ok {
+			if input.IsFinal {
+				c.removeMultiCtx(mc.id)
+			}
+			continue
+		}
+
+		passAlignment(mc.sink.Audio, StreamingOutputResponse{
+			IsFinal:             input.IsFinal,
+			NormalizedAlignment: input.NormalizedAlignment,
+			Alignment:           input.Alignment,
+			AudioDurationMs:     audioMs,
+		})
+		response := StreamingOutputMultiCtxResponse{
+			IsFinal:             input.IsFinal,
+			NormalizedAlignment: input.NormalizedAlignment,
+			Alignment:           input.Alignment,
+			AudioDurationMs:     audioMs,
+			ContextId:           mc.id,
+		}
+		if !mc.queue.push(c.ctx, c.stop, response, input.IsFinal) {
+			return
+		}
+
+		if input.IsFinal {
+			c.log.Debug("context finished", "context_id", mc.id)
+			c.removeMultiCtx(mc.id)
+		}
+	}
+}
+
+// Write the frame's audio to the context sink and return its duration in ms, false when the frame has to be skipped
+func (c *MultiClient) routeAudio(mc *multiContext, input *StreamingOutputMultiCtxRawResponse) (int, bool) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.interrupted {
+		return 0, false
+	}
+
+	var audioMs int
+	if input.Audio != "" {
+		b, err := base64.StdEncoding.DecodeString(input.Audio)
+		if err != nil {
+			c.log.Warn("dropping context, bad audio", "context_id", mc.id, "error", err)
+			c.dropContext(mc.id)
+			return 0, false
+		}
+		c.log.Debug("received audio", "context_id", mc.id, "bytes", len(b))
+		if mc.sink.Audio != nil {
+			if _, err := mc.sink.Audio.Write(b); err != nil {
+				c.log.Warn("dropping context, audio sink failed", "context_id", mc.id, "error", err)
+				c.dropContext(mc.id)
+				return 0, false
+			}
+		}
+		mc.audioBytes += len(b)
+		mc.marked = false
+		audioMs = int(c.format.Duration(len(b)).Milliseconds())
+	}
+	if mc.aligned != nil {
+		mc.aligned.AddChunk(input.Alignment, input.NormalizedAlignment, audioMs)
+	}
+
+	mc.ackedChars += len(input.Alignment.Chars)
+	var flushed bool
+	for len(mc.flushes) > 0 && mc.ackedChars >= mc.flushes[0] {
+		mc.flushes = mc.flushes[1:]
+		flushed = true
+	}
+	if flushed || input.IsFinal {
+		mc.boundary()
+	}
+	return audioMs, true
+}
+
+// Tell the sink its audio so far completes a generation or flush, once. Callers hold mu.
+func (mc *multiContext) boundary() {
+	if !mc.marked {
+		mc.marked = true
+		markBoundary(mc.sink.Audio)
+	}
+}
+
+// Record the error terminating the session, the first one wins
+func (c *MultiClient) fail(err error) {
+	c.log.Error("session failed", "error", err)
+	c.cmu.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.cmu.Unlock()
+}
+
+// Release every session context once the socket is gone
+func (c *MultiClient) dropSessionContexts() {
+	c.cmu.RLock()
+	var failed []*multiContext
+	for _, mc := range c.activeRequests {
+		if mc.session {
+			failed = append(failed, mc)
+		}
+	}
+	c.cmu.RUnlock()
+	err := c.Err()
+	for _, mc := range failed {
+		c.removeMultiCtx(mc.id)
+		if err != nil && mc.sink.OnError != nil && !mc.opening.Load() {
+			mc.sink.OnError(err)
+		}
+	}
+}
+
+// Abandon a context whose sink failed
+func (c *MultiClient) dropContext(id string) {
+	c.removeMultiCtx(id)
+	c.write(TextToSpeechInputMultiStreamingRequest{CloseContext: true, ContextID: id})
 }
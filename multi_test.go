@@ -0,0 +1,351 @@
+package elevenlabs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/clearlyip/elevenlabs-go-websockets/elevenlabstest"
+)
+
+// Connected session on srv, closed when the test ends
+func session(t *testing.T, srv *elevenlabstest.Server, opts ...elevenlabs.Option) *elevenlabs.MultiClient {
+	t.Helper()
+	opts = append([]elevenlabs.Option{elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL())}, opts...)
+	c := elevenlabs.NewMultiClient(context.Background(), "key", time.Second, opts...)
+	if err := c.Connect("voice", "model"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// Read alignment events until isFinal
+func final(t *testing.T, alignment <-chan elevenlabs.StreamingOutputMultiCtxResponse) []elevenlabs.StreamingOutputMultiCtxResponse {
+	t.Helper()
+	var events []elevenlabs.StreamingOutputMultiCtxResponse
+	for {
+		select {
+		case resp := <-alignment:
+			events = append(events, resp)
+			if resp.IsFinal {
+				return events
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no isFinal received")
+		}
+	}
+}
+
+func TestMultiContextSessionRoutesContexts(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := session(t, srv)
+
+	audio := map[string]*audioBuffer{"a": {}, "b": {}}
+	alignment := map[string]chan elevenlabs.StreamingOutputMultiCtxResponse{}
+	for id := range audio {
+		alignment[id] = make(chan elevenlabs.StreamingOutputMultiCtxResponse, 16)
+		if err := c.OpenContext(id, nil, elevenlabs.ContextSink{Audio: audio[id], Alignment: alignment[id]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, step := range []struct{ id, text string }{{"a", "one "}, {"b", "two "}, {"a", "three"}, {"b", "four"}} {
+		if err := c.SendText(step.id, step.text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id := range audio {
+		if err := c.CloseContext(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{"a": "one three", "b": "two four"}
+	for id, text := range want {
+		for _, resp := range final(t, alignment[id]) {
+			if resp.ContextId != id {
+				t.Errorf("context %q got alignment for %q", id, resp.ContextId)
+			}
+		}
+		if got := audio[id].String(); got != text {
+			t.Errorf("context %q audio = %q, want %q", id, got, text)
+		}
+	}
+
+	if err := c.SendText("a", "late"); !errors.Is(err, elevenlabs.ErrUnknownContext) {
+		t.Errorf("SendText after isFinal = %v, want ErrUnknownContext", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close = %v", err)
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Error("Done not closed after Close")
+	}
+	if err := c.OpenContext("c", nil, elevenlabs.ContextSink{}); err == nil {
+		t.Error("context opened on a closed session")
+	}
+}
+
+func TestMultiContextSessionContextError(t *testing.T) {
+	checkGoroutines(t)
+	// Replies: the init context closure, "one" for a, then an error for b instead of "two"
+	payload, _ := json.Marshal(map[string]string{"error": "max_character_limit_exceeded", "message": "too long", "contextId": "b"})
+	srv := newServer(t, elevenlabstest.WithFaults(elevenlabstest.Fault{Kind: elevenlabstest.FaultError, Conn: 1, After: 2, Once: true, Payload: payload}))
+	c := session(t, srv)
+
+	failed := make(chan error, 1)
+	alignment := make(chan elevenlabs.StreamingOutputMultiCtxResponse, 16)
+	var audio audioBuffer
+	if err := c.OpenContext("a", nil, elevenlabs.ContextSink{Audio: &audio, Alignment: alignment}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.OpenContext("b", nil, elevenlabs.ContextSink{OnError: func(err error) { failed <- err }}); err != nil {
+		t.Fatal(err)
+	}
+	c.SendText("a", "one")
+	c.SendText("b", "two")
+
+	select {
+	case err := <-failed:
+		if !errors.Is(err, elevenlabs.ErrMaxCharactersExceeded) {
+			t.Errorf("OnError(%v), want ErrMaxCharactersExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError not called")
+	}
+
+	// The other context and the session carry on
+	c.SendText("a", " again")
+	c.CloseContext("a")
+	final(t, alignment)
+	if got := audio.String(); got != "one again" {
+		t.Errorf("audio = %q", got)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close = %v", err)
+	}
+}
+
+func TestMultiContextSessionError(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t, elevenlabstest.WithFaults(elevenlabstest.ErrorFrame(1, 1, "invalid_api_key", "bad key")))
+	c := session(t, srv)
+
+	failed := make(chan error, 1)
+	if err := c.OpenContext("a", nil, elevenlabs.ContextSink{OnError: func(err error) { failed <- err }}); err != nil {
+		t.Fatal(err)
+	}
+	c.SendText("a", "one")
+
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("session still open after an error frame")
+	}
+	if err := c.Err(); !errors.Is(err, elevenlabs.ErrInvalidAPIKey) {
+		t.Errorf("Err = %v, want ErrInvalidAPIKey", err)
+	}
+	select {
+	case err := <-failed:
+		if !errors.Is(err, elevenlabs.ErrInvalidAPIKey) {
+			t.Errorf("OnError(%v), want ErrInvalidAPIKey", err)
+		}
+	default:
+		t.Error("open context not told about the session failure")
+	}
+	if err := c.SendText("a", "two"); err == nil {
+		t.Error("SendText succeeded on a failed session")
+	}
+}
+
+func TestOpenContextFailFast(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := session(t, srv)
+
+	for i := range elevenlabs.MULTI_CONTEXT_MAX_REQUESTS {
+		if err := c.OpenContext(string(rune('a'+i)), nil, elevenlabs.ContextSink{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.HasCapacity() {
+		t.Error("capacity left with every slot in use")
+	}
+	if err := c.OpenContext("z", nil, elevenlabs.ContextSink{}); !errors.Is(err, elevenlabs.ErrContextCapacity) {
+		t.Fatalf("OpenContext over capacity = %v, want ErrContextCapacity", err)
+	}
+	if err := c.OpenContext("a", nil, elevenlabs.ContextSink{}); err == nil {
+		t.Error("context id opened twice")
+	}
+
+	// Closing frees the slot straight away, before the server confirms it
+	if err := c.CloseContext("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.OpenContext("z", nil, elevenlabs.ContextSink{}); err != nil {
+		t.Errorf("OpenContext after a closure = %v", err)
+	}
+}
+
+func TestOpenContextWait(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := session(t, srv, elevenlabs.WithAdmission(elevenlabs.AdmissionWait))
+
+	for i := range elevenlabs.MULTI_CONTEXT_MAX_REQUESTS {
+		if err := c.OpenContext(string(rune('a'+i)), nil, elevenlabs.ContextSink{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.OpenContextWait(ctx, "y", nil, elevenlabs.ContextSink{})
+	if !errors.Is(err, elevenlabs.ErrContextCapacity) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("OpenContextWait = %v, want ErrContextCapacity and DeadlineExceeded", err)
+	}
+
+	admitted := make(chan error, 1)
+	go func() {
+		admitted <- c.OpenContextWait(context.Background(), "z", nil, elevenlabs.ContextSink{})
+	}()
+	select {
+	case err := <-admitted:
+		t.Fatalf("OpenContextWait returned %v before a slot was free", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.CloseContext("a")
+	select {
+	case err := <-admitted:
+		if err != nil {
+			t.Errorf("OpenContextWait = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting context not admitted after a closure")
+	}
+}
+
+func TestOpenContextSameIDConcurrently(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := session(t, srv)
+
+	results := make(chan error, 8)
+	for range cap(results) {
+		go func() { results <- c.OpenContext("a", nil, elevenlabs.ContextSink{}) }()
+	}
+	var opened int
+	for range cap(results) {
+		if <-results == nil {
+			opened++
+		}
+	}
+	if opened != 1 {
+		t.Errorf("context opened %d times", opened)
+	}
+}
+
+func TestOpenContextWaitReservesID(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := session(t, srv, elevenlabs.WithAdmission(elevenlabs.AdmissionWait))
+	for i := range elevenlabs.MULTI_CONTEXT_MAX_REQUESTS {
+		if err := c.OpenContext(string(rune('a'+i)), nil, elevenlabs.ContextSink{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiting := make(chan error, 1)
+	go func() { waiting <- c.OpenContextWait(ctx, "z", nil, elevenlabs.ContextSink{}) }()
+	time.Sleep(50 * time.Millisecond)
+
+	// A second open of the id fails on the id rather than joining the wait
+	done, stop := context.WithCancel(context.Background())
+	stop()
+	if err := c.OpenContextWait(done, "z", nil, elevenlabs.ContextSink{}); err == nil || errors.Is(err, elevenlabs.ErrContextCapacity) {
+		t.Errorf("second OpenContextWait = %v, want the id taken", err)
+	}
+	if err := c.SendText("z", "early"); !errors.Is(err, elevenlabs.ErrUnknownContext) {
+		t.Errorf("SendText to a waiting context = %v, want ErrUnknownContext", err)
+	}
+
+	// Giving up frees the id, the slot count is untouched
+	cancel()
+	if err := <-waiting; !errors.Is(err, context.Canceled) {
+		t.Fatalf("OpenContextWait = %v", err)
+	}
+	c.CloseContext("a")
+	if err := c.OpenContext("z", nil, elevenlabs.ContextSink{}); err != nil {
+		t.Errorf("OpenContext after the wait gave up = %v", err)
+	}
+	if c.HasCapacity() {
+		t.Error("slot left over after the reservation was dropped")
+	}
+}
+
+func TestMultiContextSessionBoundaries(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := session(t, srv)
+
+	var rec formatRecorder
+	alignment := make(chan elevenlabs.StreamingOutputMultiCtxResponse, 16)
+	if err := c.OpenContext("a", nil, elevenlabs.ContextSink{Audio: &rec, Alignment: alignment}); err != nil {
+		t.Fatal(err)
+	}
+	c.SendText("a", "one ")
+	c.Flush("a")
+	c.SendText("a", "two")
+	c.Flush("a")
+	c.CloseContext("a")
+	final(t, alignment)
+
+	// The final flush and isFinal close the same audio, it gets a single boundary
+	if got := rec.String(); got != "[mp3_44100_128]one |two|" {
+		t.Errorf("sink saw %q", got)
+	}
+}
+
+func TestRemovedContextCountsCoalescedEvents(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c := session(t, srv, elevenlabs.WithAlignmentDelivery(elevenlabs.DeliverCoalesce))
+
+	// Nobody reads, the first event fills the channel and the others coalesce behind it
+	var audio audioBuffer
+	alignment := make(chan elevenlabs.StreamingOutputMultiCtxResponse, 1)
+	if err := c.OpenContext("a", nil, elevenlabs.ContextSink{Audio: &audio, Alignment: alignment}); err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"one ", "two ", "three"} {
+		c.SendText("a", text)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for audio.String() != "one two three" {
+		if time.Now().After(deadline) {
+			t.Fatalf("audio = %q", audio.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The interrupted context is removed on isFinal, its pending event is never delivered
+	if _, err := c.Interrupt("a"); err != nil {
+		t.Fatal(err)
+	}
+	for c.DroppedAlignments() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("dropped = %d, want the pending event counted", c.DroppedAlignments())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := len(alignment); n != 1 {
+		t.Errorf("%d events delivered", n)
+	}
+}
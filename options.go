@@ -0,0 +1,101 @@
+package elevenlabs
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Behaviour when a context is opened while all MULTI_CONTEXT_MAX_REQUESTS slots are in use
+type AdmissionMode int
+
+const (
+	AdmissionFailFast AdmissionMode = iota // Return ErrContextCapacity immediately
+	AdmissionWait                          // Block until a slot frees up or the context is done
+)
+
+type options struct {
+	admission    AdmissionMode
+	baseURLHTTPS string
+	baseURLWSS   string
+	dialer       *websocket.Dialer
+	httpClient   *http.Client
+	reconnect    *ReconnectPolicy
+	logger       *slog.Logger
+	logText      bool
+	delivery     AlignmentDelivery
+	dictionaries []PronunciationDictionaryLocators
+	retry        RetryPolicy
+	responseHook func(ResponseMeta)
+	quota        *QuotaTracker
+	tenant       string
+}
+
+type Option func(*options)
+
+func newOptions(opts []Option) options {
+	o := options{
+		baseURLHTTPS: ELEVEN_BASEURL_HTTPS,
+		baseURLWSS:   ELEVEN_BASEURL_WSS,
+		retry:        DEFAULT_RETRY_POLICY,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Websocket dialer, the default one uses timeout for the handshake
+func (o *options) wsDialer(timeout time.Duration) *websocket.Dialer {
+	if o.dialer != nil {
+		return o.dialer
+	}
+	d := *websocket.DefaultDialer
+	d.HandshakeTimeout = timeout
+	return &d
+}
+
+// REST client, request timeouts are applied per attempt through the request context
+func (o *options) restClient() *http.Client {
+	if o.httpClient != nil {
+		return o.httpClient
+	}
+	return http.DefaultClient
+}
+
+// REST base URL, e.g. an EU residency endpoint or a local fake
+func WithBaseURL(url string) Option {
+	return func(o *options) {
+		o.baseURLHTTPS = strings.TrimSuffix(url, "/")
+	}
+}
+
+// Websocket base URL, e.g. an EU residency endpoint or a local fake
+func WithWebsocketBaseURL(url string) Option {
+	return func(o *options) {
+		o.baseURLWSS = strings.TrimSuffix(url, "/")
+	}
+}
+
+// Websocket dialer for TLS config, proxies or custom net dialing
+func WithDialer(d *websocket.Dialer) Option {
+	return func(o *options) {
+		o.dialer = d
+	}
+}
+
+// HTTP client for REST requests
+func WithHTTPClient(hc *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = hc
+	}
+}
+
+func WithAdmission(mode AdmissionMode) Option {
+	return func(o *options) {
+		o.admission = mode
+	}
+}
@@ -0,0 +1,101 @@
+package elevenlabs_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/gorilla/websocket"
+)
+
+// Transport counting the requests it forwards
+type countingTransport struct {
+	n atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.n.Add(1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestBaseURLAndHTTPClient(t *testing.T) {
+	paths := make(chan string, 1)
+	srv, _ := scripted(t, func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+	})
+	var transport countingTransport
+	c := restClient(t, srv.URL+"/", elevenlabs.WithHTTPClient(&http.Client{Transport: &transport}))
+
+	if _, err := c.Request(context.Background(), http.MethodGet, "/user", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if path := <-paths; path != "/user" {
+		t.Errorf("path = %q", path)
+	}
+	if n := transport.n.Load(); n != 1 {
+		t.Errorf("custom client used for %d requests", n)
+	}
+}
+
+func TestWebsocketBaseURLAndDialer(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	var dials atomic.Int32
+	dialer := *websocket.DefaultDialer
+	dialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Add(1)
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second,
+		elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()+"/"), elevenlabs.WithDialer(&dialer))
+
+	text := make(chan string, 1)
+	text <- "hello"
+	close(text)
+	var audio audioBuffer
+	if err := wait(t, stream(c, text, nil, &audio)); err != nil {
+		t.Fatalf("StreamingRequest = %v", err)
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("custom dialer used for %d connections", n)
+	}
+	reqs := srv.Requests()
+	if len(reqs) == 0 || reqs[0].VoiceID != "voice" || reqs[0].Header.Get("xi-api-key") != "key" {
+		t.Errorf("requests = %+v", reqs)
+	}
+}
+
+func TestNewMultiContextSessionWithoutTimeout(t *testing.T) {
+	checkGoroutines(t)
+	srv := newServer(t)
+	c, err := elevenlabs.NewMultiContextSession(context.Background(), "key", 0, "voice", "model",
+		[]elevenlabs.QueryFunc{elevenlabs.FormatPCM_16000.Query()}, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := c.Format(); f.SampleRate != 16000 {
+		t.Errorf("format = %+v", f)
+	}
+
+	alignment := make(chan elevenlabs.StreamingOutputMultiCtxResponse, 16)
+	if err := c.OpenContext("a", nil, elevenlabs.ContextSink{Alignment: alignment}); err != nil {
+		t.Fatal(err)
+	}
+	c.SendText("a", "hello")
+
+	// Without a timeout Close waits for the server to finish the open context
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close = %v", err)
+	}
+	var finished bool
+	for len(alignment) > 0 {
+		finished = (<-alignment).IsFinal
+	}
+	if !finished {
+		t.Error("Close returned before the context finished")
+	}
+}
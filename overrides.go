@@ -0,0 +1,112 @@
+package elevenlabs
+
+import "encoding/json"
+
+// Voice settings used by the API when a request does not set them
+func DefaultVoiceSettings() VoiceSettings {
+	return VoiceSettings{
+		SimilarityBoost: 0.75,
+		Stability:       0.5,
+		SpeakerBoost:    true,
+		Speed:           1.0,
+	}
+}
+
+// Changes applied from a text chunk on, nil fields keep their current value
+type ChunkOverrides struct {
+	Stability       *float32
+	SimilarityBoost *float32
+	Style           *float32
+	Speed           *float32
+	SpeakerBoost    *bool
+
+	ChunkLengthSchedule  []int // Sent as generation_config with the chunk
+	TryTriggerGeneration bool  // Ask the server to generate audio for the buffered text
+}
+
+// Text chunk carrying overrides
+func TextInputWith(text string, overrides *ChunkOverrides) InputEvent {
+	return InputEvent{Kind: InputText, Text: text, Overrides: overrides}
+}
+
+func (o *ChunkOverrides) voiceChanged() bool {
+	return o.Stability != nil || o.SimilarityBoost != nil || o.Style != nil || o.Speed != nil || o.SpeakerBoost != nil
+}
+
+// Merge the voice overrides into current, a nil current starts from DefaultVoiceSettings.
+// The API expects complete voice_settings, so the merged settings are always a full copy.
+func (o *ChunkOverrides) mergeVoice(current *VoiceSettings) *VoiceSettings {
+	merged := DefaultVoiceSettings()
+	if current != nil {
+		merged = *current
+	}
+	if o.Stability != nil {
+		merged.Stability = *o.Stability
+	}
+	if o.SimilarityBoost != nil {
+		merged.SimilarityBoost = *o.SimilarityBoost
+	}
+	if o.Style != nil {
+		merged.Style = *o.Style
+	}
+	if o.Speed != nil {
+		merged.Speed = *o.Speed
+	}
+	if o.SpeakerBoost != nil {
+		merged.SpeakerBoost = *o.SpeakerBoost
+	}
+	return &merged
+}
+
+// voice_settings with every field present, merged settings keep Style=0 and SpeakerBoost=false
+type voiceSettingsWire struct {
+	SimilarityBoost float32 `json:"similarity_boost"`
+	Stability       float32 `json:"stability"`
+	Style           float32 `json:"style"`
+	SpeakerBoost    bool    `json:"use_speaker_boost"`
+	Speed           float32 `json:"speed"`
+}
+
+// Frames carrying merged voice settings send them complete, VoiceSettings itself keeps omitting zero values
+func (r TextToSpeechInputMultiStreamingRequest) MarshalJSON() ([]byte, error) {
+	type plain TextToSpeechInputMultiStreamingRequest
+	if !r.mergedVoice || r.VoiceSettings == nil {
+		return json.Marshal(plain(r))
+	}
+	v := r.VoiceSettings
+	return json.Marshal(struct {
+		plain
+		VoiceSettings voiceSettingsWire `json:"voice_settings"`
+	}{plain(r), voiceSettingsWire{v.SimilarityBoost, v.Stability, v.Style, v.SpeakerBoost, v.Speed}})
+}
+
+// Tracks the voice settings of one context and stamps changes onto outgoing text frames
+type chunkSettings struct {
+	voice   *VoiceSettings
+	merged  bool // voice came from overrides
+	pending bool // voice changed since the last text frame
+}
+
+func (s *chunkSettings) setVoice(v *VoiceSettings) {
+	s.voice = v
+	s.merged = false
+	s.pending = true
+}
+
+func (s *chunkSettings) apply(req *TextToSpeechInputMultiStreamingRequest, o *ChunkOverrides) {
+	if o != nil {
+		if o.voiceChanged() {
+			s.setVoice(o.mergeVoice(s.voice))
+			s.merged = true
+		}
+		if o.ChunkLengthSchedule != nil {
+			req.GenerationConfig = &GenerationConfig{ChunkLengthSchedule: o.ChunkLengthSchedule}
+		}
+		req.TryTriggerGeneration = o.TryTriggerGeneration
+	}
+	if s.pending {
+		req.VoiceSettings = s.voice
+		req.mergedVoice = s.merged
+		s.pending = false
+	}
+}
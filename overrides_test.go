@@ -0,0 +1,96 @@
+package elevenlabs
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestMergedVoiceSettingsKeepZeroValues(t *testing.T) {
+	s := chunkSettings{voice: &VoiceSettings{SimilarityBoost: 0.9, Stability: 0.5, Style: 0.4, SpeakerBoost: true, Speed: 1.1}}
+	req := TextToSpeechInputMultiStreamingRequest{Text: "hello "}
+	s.apply(&req, &ChunkOverrides{Style: ptr[float32](0), SpeakerBoost: ptr(false), Stability: ptr[float32](0.2)})
+
+	voice := func(v any) string {
+		t.Helper()
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var frame struct {
+			VoiceSettings json.RawMessage `json:"voice_settings"`
+		}
+		json.Unmarshal(b, &frame)
+		return string(frame.VoiceSettings)
+	}
+	want := `{"similarity_boost":0.9,"stability":0.2,"style":0,"use_speaker_boost":false,"speed":1.1}`
+	if got := voice(req); got != want {
+		t.Errorf("merged settings = %s, want %s", got, want)
+	}
+
+	// Replayed after a reconnect, the settings still go out complete
+	var b replayBuffer
+	b.add(req)
+	b.add(TextToSpeechInputMultiStreamingRequest{Text: "world"})
+	b.ack(false, len(req.Text), true)
+	if got := voice(b.pending()[0]); got != want {
+		t.Errorf("replayed settings = %s, want %s", got, want)
+	}
+
+	// The settings themselves and frames set by the caller keep omitting zero values
+	if got, _ := json.Marshal(req.VoiceSettings); string(got) != `{"similarity_boost":0.9,"stability":0.2,"speed":1.1}` {
+		t.Errorf("merged VoiceSettings = %s", got)
+	}
+	plain := TextToSpeechInputMultiStreamingRequest{Text: "hi", VoiceSettings: &VoiceSettings{SimilarityBoost: 0.9, Stability: 0.5}}
+	if got := voice(plain); got != `{"similarity_boost":0.9,"stability":0.5}` {
+		t.Errorf("plain settings = %s", got)
+	}
+}
+
+func TestMergeVoiceStartsFromDefaults(t *testing.T) {
+	merged := (&ChunkOverrides{Speed: ptr[float32](1.2)}).mergeVoice(nil)
+	want := DefaultVoiceSettings()
+	want.Speed = 1.2
+	if *merged != want {
+		t.Errorf("merged = %+v, want %+v", *merged, want)
+	}
+}
+
+func TestChunkSettingsApply(t *testing.T) {
+	s := chunkSettings{voice: &VoiceSettings{Stability: 0.5, SimilarityBoost: 0.5}}
+	frame := func(o *ChunkOverrides) TextToSpeechInputMultiStreamingRequest {
+		var req TextToSpeechInputMultiStreamingRequest
+		s.apply(&req, o)
+		return req
+	}
+
+	req := frame(&ChunkOverrides{Stability: ptr[float32](0.9), ChunkLengthSchedule: []int{50, 120}, TryTriggerGeneration: true})
+	if req.VoiceSettings == nil || req.VoiceSettings.Stability != 0.9 || req.VoiceSettings.SimilarityBoost != 0.5 {
+		t.Errorf("voice settings = %+v", req.VoiceSettings)
+	}
+	if req.GenerationConfig == nil || !slices.Equal(req.GenerationConfig.ChunkLengthSchedule, []int{50, 120}) || !req.TryTriggerGeneration {
+		t.Errorf("generation overrides = %+v, %v", req.GenerationConfig, req.TryTriggerGeneration)
+	}
+
+	// Unchanged settings are not repeated, generation overrides only apply to their chunk
+	req = frame(nil)
+	if req.VoiceSettings != nil || req.GenerationConfig != nil || req.TryTriggerGeneration {
+		t.Errorf("plain chunk = %+v", req)
+	}
+
+	// Generation-only overrides leave the voice alone, later voice overrides build on the last merge
+	if req = frame(&ChunkOverrides{TryTriggerGeneration: true}); req.VoiceSettings != nil {
+		t.Errorf("voice settings sent without a voice change: %+v", req.VoiceSettings)
+	}
+	req = frame(&ChunkOverrides{Speed: ptr[float32](0.8)})
+	if v := req.VoiceSettings; v == nil || v.Stability != 0.9 || v.Speed != 0.8 {
+		t.Errorf("voice settings = %+v", v)
+	}
+
+	s.setVoice(&VoiceSettings{Stability: 0.1})
+	if req = frame(nil); req.VoiceSettings == nil || req.VoiceSettings.Stability != 0.1 {
+		t.Errorf("replaced voice settings = %+v", req.VoiceSettings)
+	}
+}
@@ -0,0 +1,175 @@
+// Package pls parses and validates W3C Pronunciation Lexicon (PLS) files before they are uploaded as pronunciation dictionaries.
+package pls
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+const NAMESPACE = "http://www.w3.org/2005/01/pronunciation-lexicon"
+const VERSION = "1.0"
+
+// Phonetic alphabets understood by the API
+const (
+	ALPHABET_IPA = "ipa"
+	ALPHABET_CMU = "cmu-arpabet"
+)
+
+type Lexicon struct {
+	XMLName  xml.Name `xml:"lexicon"`
+	Version  string   `xml:"version,attr"`
+	Alphabet string   `xml:"alphabet,attr"`
+	Lang     string   `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Lexemes  []Lexeme `xml:"lexeme"`
+}
+
+type Lexeme struct {
+	Graphemes []string  `xml:"grapheme"`
+	Phonemes  []Phoneme `xml:"phoneme"`
+	Aliases   []string  `xml:"alias"`
+}
+
+type Phoneme struct {
+	Value    string `xml:",chardata"`
+	Alphabet string `xml:"alphabet,attr,omitempty"` // Overrides the lexicon alphabet
+}
+
+func New(lang string, alphabet string) *Lexicon {
+	return &Lexicon{Version: VERSION, Alphabet: alphabet, Lang: lang}
+}
+
+// Parse and validate a lexicon
+func Parse(r io.Reader) (*Lexicon, error) {
+	var l Lexicon
+	if err := xml.NewDecoder(r).Decode(&l); err != nil {
+		return nil, fmt.Errorf("parsing lexicon: %w", err)
+	}
+	if l.XMLName.Space != NAMESPACE {
+		return nil, fmt.Errorf("lexicon namespace %q, expected %q", l.XMLName.Space, NAMESPACE)
+	}
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Check the lexicon against PLS 1.0 and the alphabets supported by the API, all problems are joined
+func (l *Lexicon) Validate() error {
+	var errs []error
+	if l.Version != VERSION {
+		errs = append(errs, fmt.Errorf("lexicon version %q, expected %q", l.Version, VERSION))
+	}
+	if l.Lang == "" {
+		errs = append(errs, fmt.Errorf("lexicon xml:lang missing"))
+	}
+	if err := checkAlphabet(l.Alphabet); err != nil {
+		errs = append(errs, fmt.Errorf("lexicon: %w", err))
+	}
+
+	seen := map[string]int{}
+	for i, lx := range l.Lexemes {
+		n := i + 1
+		if len(lx.Graphemes) == 0 {
+			errs = append(errs, fmt.Errorf("lexeme %d: no grapheme", n))
+		}
+		if len(lx.Phonemes) == 0 && len(lx.Aliases) == 0 {
+			errs = append(errs, fmt.Errorf("lexeme %d: no phoneme or alias", n))
+		}
+		for _, g := range lx.Graphemes {
+			g = strings.TrimSpace(g)
+			if g == "" {
+				errs = append(errs, fmt.Errorf("lexeme %d: empty grapheme", n))
+				continue
+			}
+			if prev, ok := seen[g]; ok {
+				errs = append(errs, fmt.Errorf("lexeme %d: grapheme %q already defined by lexeme %d", n, g, prev))
+			}
+			seen[g] = n
+		}
+		for _, p := range lx.Phonemes {
+			if strings.TrimSpace(p.Value) == "" {
+				errs = append(errs, fmt.Errorf("lexeme %d: empty phoneme", n))
+			}
+			if p.Alphabet != "" {
+				if err := checkAlphabet(p.Alphabet); err != nil {
+					errs = append(errs, fmt.Errorf("lexeme %d: %w", n, err))
+				}
+			}
+		}
+		for _, a := range lx.Aliases {
+			if strings.TrimSpace(a) == "" {
+				errs = append(errs, fmt.Errorf("lexeme %d: empty alias", n))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkAlphabet(alphabet string) error {
+	switch alphabet {
+	case ALPHABET_IPA, ALPHABET_CMU:
+		return nil
+	}
+	return fmt.Errorf("unsupported alphabet %q", alphabet)
+}
+
+// Add a lexeme pronouncing grapheme with an alias
+func (l *Lexicon) AddAlias(grapheme string, alias string) {
+	l.Lexemes = append(l.Lexemes, Lexeme{Graphemes: []string{grapheme}, Aliases: []string{alias}})
+}
+
+// Add a lexeme pronouncing grapheme with a phoneme in the lexicon alphabet
+func (l *Lexicon) AddPhoneme(grapheme string, phoneme string) {
+	l.Lexemes = append(l.Lexemes, Lexeme{Graphemes: []string{grapheme}, Phonemes: []Phoneme{{Value: phoneme}}})
+}
+
+// Write the lexicon as a PLS document
+func (l *Lexicon) Encode(w io.Writer) error {
+	// encoding/xml cannot write the xml:lang attribute it reads, so the root is spelled out
+	out := struct {
+		XMLName  xml.Name `xml:"lexicon"`
+		Version  string   `xml:"version,attr"`
+		Xmlns    string   `xml:"xmlns,attr"`
+		Alphabet string   `xml:"alphabet,attr"`
+		Lang     string   `xml:"xml:lang,attr"`
+		Lexemes  []Lexeme `xml:"lexeme"`
+	}{Version: l.Version, Xmlns: NAMESPACE, Alphabet: l.Alphabet, Lang: l.Lang, Lexemes: l.Lexemes}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Rules for AddPronunciationDictionaryFromRules, one per grapheme using the first phoneme or alias
+func (l *Lexicon) Rules() []elevenlabs.PronunciationRule {
+	var rules []elevenlabs.PronunciationRule
+	for _, lx := range l.Lexemes {
+		for _, g := range lx.Graphemes {
+			g = strings.TrimSpace(g)
+			switch {
+			case len(lx.Phonemes) > 0:
+				p := lx.Phonemes[0]
+				alphabet := p.Alphabet
+				if alphabet == "" {
+					alphabet = l.Alphabet
+				}
+				rules = append(rules, elevenlabs.PhonemeRule(g, strings.TrimSpace(p.Value), alphabet))
+			case len(lx.Aliases) > 0:
+				rules = append(rules, elevenlabs.AliasRule(g, strings.TrimSpace(lx.Aliases[0])))
+			}
+		}
+	}
+	return rules
+}
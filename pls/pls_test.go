@@ -0,0 +1,112 @@
+package pls_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/clearlyip/elevenlabs-go-websockets/pls"
+)
+
+const lexicon = `<?xml version="1.0" encoding="UTF-8"?>
+<lexicon version="1.0" xmlns="http://www.w3.org/2005/01/pronunciation-lexicon" alphabet="ipa" xml:lang="en-US">
+  <lexeme>
+    <grapheme>tomato</grapheme>
+    <grapheme> Tomato </grapheme>
+    <phoneme>təˈmɑːtoʊ</phoneme>
+  </lexeme>
+  <lexeme>
+    <grapheme>Aleph</grapheme>
+    <phoneme alphabet="cmu-arpabet">AA L EH F</phoneme>
+  </lexeme>
+  <lexeme>
+    <grapheme>UN</grapheme>
+    <alias>United Nations</alias>
+  </lexeme>
+</lexicon>`
+
+func TestParseRules(t *testing.T) {
+	l, err := pls.Parse(strings.NewReader(lexicon))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Lang != "en-US" || l.Alphabet != pls.ALPHABET_IPA || len(l.Lexemes) != 3 {
+		t.Fatalf("lexicon = %+v", l)
+	}
+
+	want := []elevenlabs.PronunciationRule{
+		elevenlabs.PhonemeRule("tomato", "təˈmɑːtoʊ", pls.ALPHABET_IPA),
+		elevenlabs.PhonemeRule("Tomato", "təˈmɑːtoʊ", pls.ALPHABET_IPA),
+		elevenlabs.PhonemeRule("Aleph", "AA L EH F", pls.ALPHABET_CMU),
+		elevenlabs.AliasRule("UN", "United Nations"),
+	}
+	if got := l.Rules(); !reflect.DeepEqual(got, want) {
+		t.Errorf("rules = %+v", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+		want []string
+	}{
+		{"not xml", "lexicon", []string{"parsing lexicon"}},
+		{"namespace", `<lexicon version="1.0" alphabet="ipa" xml:lang="en"/>`, []string{"namespace"}},
+		{"header", `<lexicon version="2.0" xmlns="` + pls.NAMESPACE + `" alphabet="x-sampa"/>`,
+			[]string{`version "2.0"`, "xml:lang missing", `unsupported alphabet "x-sampa"`}},
+		{"lexemes", `<lexicon version="1.0" xmlns="` + pls.NAMESPACE + `" alphabet="ipa" xml:lang="en">
+			<lexeme><phoneme>a</phoneme></lexeme>
+			<lexeme><grapheme>a</grapheme></lexeme>
+			<lexeme><grapheme> </grapheme><alias> </alias></lexeme>
+			<lexeme><grapheme>a</grapheme><phoneme alphabet="sampa"> </phoneme></lexeme>
+		</lexicon>`, []string{
+			"lexeme 1: no grapheme",
+			"lexeme 2: no phoneme or alias",
+			"lexeme 3: empty grapheme",
+			"lexeme 3: empty alias",
+			`lexeme 4: grapheme "a" already defined by lexeme 2`,
+			"lexeme 4: empty phoneme",
+			`lexeme 4: unsupported alphabet "sampa"`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pls.Parse(strings.NewReader(tt.xml))
+			if err == nil {
+				t.Fatal("Parse succeeded")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q misses %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	l := pls.New("en-GB", pls.ALPHABET_CMU)
+	l.AddPhoneme("Aleph", "AA L EH F")
+	l.AddAlias("W3C", "World Wide Web Consortium")
+	if err := l.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := l.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `xml:lang="en-GB"`) {
+		t.Errorf("encoded lexicon misses xml:lang:\n%s", buf.String())
+	}
+	parsed, err := pls.Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse(Encode) = %v", err)
+	}
+	if !reflect.DeepEqual(parsed.Rules(), l.Rules()) || parsed.Lang != "en-GB" || parsed.Version != pls.VERSION {
+		t.Errorf("round trip = %+v", parsed)
+	}
+}
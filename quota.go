@@ -0,0 +1,363 @@
+package elevenlabs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrBudgetExceeded = errors.New("character budget exceeded")
+
+// Fractions of a hard limit reported through the warning handler
+var DEFAULT_QUOTA_THRESHOLDS = []float64{0.5, 0.8, 0.9}
+
+type QuotaPeriod string
+
+const (
+	QuotaDaily   QuotaPeriod = "daily"
+	QuotaMonthly QuotaPeriod = "monthly"
+	QuotaAccount QuotaPeriod = "account" // Subscription character count, as of the last Reconcile
+)
+
+// Character limits of a tenant, 0 disables a limit. Soft limits only warn, hard limits reject new requests.
+type Budget struct {
+	DailySoft   int
+	DailyHard   int
+	MonthlySoft int
+	MonthlyHard int
+}
+
+// Reported once per tenant, period and threshold
+type QuotaWarning struct {
+	Tenant    string
+	Period    QuotaPeriod
+	Used      int
+	Limit     int
+	Threshold float64 // Fraction of the hard limit, 0 for soft limit warnings
+	Soft      bool
+}
+
+type QuotaUsage struct {
+	Tenant  string
+	Daily   int
+	Monthly int
+	Total   int // Since the tracker was created
+}
+
+// Subscription usage as of the last Reconcile
+type AccountUsage struct {
+	CharacterCount int
+	CharacterLimit int
+	CanExtend      bool
+	Untracked      int // Characters billed since the previous Reconcile that no tracked request accounts for
+	At             time.Time
+}
+
+type QuotaOption func(*QuotaTracker)
+
+// Budget of one tenant, replacing the default budget
+func WithBudget(tenant string, b Budget) QuotaOption {
+	return func(q *QuotaTracker) {
+		q.budgets[tenant] = b
+	}
+}
+
+// Budget of tenants without their own
+func WithDefaultBudget(b Budget) QuotaOption {
+	return func(q *QuotaTracker) {
+		q.defaultBudget = b
+	}
+}
+
+func WithQuotaThresholds(thresholds ...float64) QuotaOption {
+	return func(q *QuotaTracker) {
+		q.thresholds = thresholds
+	}
+}
+
+// Called without locks held, from the goroutine that recorded the usage
+func WithQuotaWarning(fn func(QuotaWarning)) QuotaOption {
+	return func(q *QuotaTracker) {
+		q.onWarning = fn
+	}
+}
+
+// Location of the daily and monthly period boundaries, UTC by default
+func WithQuotaLocation(loc *time.Location) QuotaOption {
+	return func(q *QuotaTracker) {
+		q.loc = loc
+	}
+}
+
+// Counts characters sent per tenant and enforces budgets before requests are dialed.
+// Attach it with WithQuota, a nil tracker accepts everything.
+type QuotaTracker struct {
+	mu            sync.Mutex
+	budgets       map[string]Budget
+	defaultBudget Budget
+	thresholds    []float64
+	onWarning     func(QuotaWarning)
+	loc           *time.Location
+	now           func() time.Time
+	tenants       map[string]*tenantUsage
+	account       *AccountUsage
+	accountWarned map[string]bool
+	tracked       int // Characters recorded since the last Reconcile
+}
+
+type tenantUsage struct {
+	day     string
+	month   string
+	daily   int
+	monthly int
+	total   int
+	warned  map[string]bool // Warnings reported in the current periods
+}
+
+func NewQuotaTracker(opts ...QuotaOption) *QuotaTracker {
+	q := &QuotaTracker{
+		budgets:       map[string]Budget{},
+		thresholds:    DEFAULT_QUOTA_THRESHOLDS,
+		loc:           time.UTC,
+		now:           time.Now,
+		tenants:       map[string]*tenantUsage{},
+		accountWarned: map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// Tracker consulted and charged by every streaming and REST request
+func WithQuota(q *QuotaTracker) Option {
+	return func(o *options) {
+		o.quota = q
+	}
+}
+
+// Tenant the client's requests are attributed to, the API key by default
+func WithTenant(tenant string) Option {
+	return func(o *options) {
+		o.tenant = tenant
+	}
+}
+
+// Copy of the client attributing its requests to tenant
+func (c *Client) ForTenant(tenant string) *Client {
+	cc := *c
+	cc.opts.tenant = tenant
+	return &cc
+}
+
+// Tenant of requests made with apiKey, keys are reduced to a hash prefix so they never reach logs
+func (o *options) tenantFor(apiKey string) string {
+	if o.tenant != "" {
+		return o.tenant
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return "key:" + hex.EncodeToString(sum[:4])
+}
+
+// Characters billed for a text frame, whitespace-only keep-alive frames are free
+func billedChars(text string) int {
+	if strings.TrimSpace(text) == "" {
+		return 0
+	}
+	return utf8.RuneCountInString(text)
+}
+
+func (q *QuotaTracker) budget(tenant string) Budget {
+	if b, ok := q.budgets[tenant]; ok {
+		return b
+	}
+	return q.defaultBudget
+}
+
+// Usage of tenant, rolled over to the current periods. Callers hold mu.
+func (q *QuotaTracker) usage(tenant string) *tenantUsage {
+	now := q.now().In(q.loc)
+	day, month := now.Format(time.DateOnly), now.Format("2006-01")
+
+	u, ok := q.tenants[tenant]
+	if !ok {
+		u = &tenantUsage{warned: map[string]bool{}}
+		q.tenants[tenant] = u
+	}
+	if u.day != day {
+		u.day, u.daily = day, 0
+		clearWarnings(u.warned, QuotaDaily)
+	}
+	if u.month != month {
+		u.month, u.monthly = month, 0
+		clearWarnings(u.warned, QuotaMonthly)
+	}
+	return u
+}
+
+// Error when tenant has reached a hard limit or the account is out of characters
+func (q *QuotaTracker) Check(tenant string) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	b := q.budget(tenant)
+	u := q.usage(tenant)
+	if b.DailyHard > 0 && u.daily >= b.DailyHard {
+		return fmt.Errorf("%w: tenant %s used %d of %d daily characters", ErrBudgetExceeded, tenant, u.daily, b.DailyHard)
+	}
+	if b.MonthlyHard > 0 && u.monthly >= b.MonthlyHard {
+		return fmt.Errorf("%w: tenant %s used %d of %d monthly characters", ErrBudgetExceeded, tenant, u.monthly, b.MonthlyHard)
+	}
+	if a := q.account; a != nil && a.CharacterLimit > 0 && !a.CanExtend && a.CharacterCount+q.tracked >= a.CharacterLimit {
+		return fmt.Errorf("%w: account used %d of %d characters", ErrBudgetExceeded, a.CharacterCount+q.tracked, a.CharacterLimit)
+	}
+	return nil
+}
+
+// Record characters sent for tenant
+func (q *QuotaTracker) Add(tenant string, chars int) {
+	if q == nil || chars <= 0 {
+		return
+	}
+	q.mu.Lock()
+	b := q.budget(tenant)
+	u := q.usage(tenant)
+	u.daily += chars
+	u.monthly += chars
+	u.total += chars
+	q.tracked += chars
+
+	var warnings []QuotaWarning
+	warnings = q.crossed(warnings, u.warned, tenant, QuotaDaily, u.daily, b.DailySoft, b.DailyHard)
+	warnings = q.crossed(warnings, u.warned, tenant, QuotaMonthly, u.monthly, b.MonthlySoft, b.MonthlyHard)
+	if a := q.account; a != nil && a.CharacterLimit > 0 {
+		warnings = q.crossed(warnings, q.accountWarned, tenant, QuotaAccount, a.CharacterCount+q.tracked, 0, a.CharacterLimit)
+	}
+	q.mu.Unlock()
+
+	q.warn(warnings)
+}
+
+// Warnings for limits reached by used that were not reported yet in this period. Callers hold mu.
+func (q *QuotaTracker) crossed(warnings []QuotaWarning, warned map[string]bool, tenant string, period QuotaPeriod, used int, soft int, hard int) []QuotaWarning {
+	once := func(id string, w QuotaWarning) {
+		id = string(period) + "/" + id
+		if !warned[id] {
+			warned[id] = true
+			warnings = append(warnings, w)
+		}
+	}
+	if soft > 0 && used >= soft {
+		once("soft", QuotaWarning{Tenant: tenant, Period: period, Used: used, Limit: soft, Soft: true})
+	}
+	if hard > 0 {
+		for _, t := range q.thresholds {
+			if float64(used) >= t*float64(hard) {
+				once(fmt.Sprint(t), QuotaWarning{Tenant: tenant, Period: period, Used: used, Limit: hard, Threshold: t})
+			}
+		}
+	}
+	return warnings
+}
+
+func clearWarnings(warned map[string]bool, period QuotaPeriod) {
+	for id := range warned {
+		if strings.HasPrefix(id, string(period)+"/") {
+			delete(warned, id)
+		}
+	}
+}
+
+func (q *QuotaTracker) warn(warnings []QuotaWarning) {
+	if q.onWarning == nil {
+		return
+	}
+	for _, w := range warnings {
+		q.onWarning(w)
+	}
+}
+
+func (q *QuotaTracker) Usage(tenant string) QuotaUsage {
+	if q == nil {
+		return QuotaUsage{Tenant: tenant}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	u := q.usage(tenant)
+	return QuotaUsage{Tenant: tenant, Daily: u.daily, Monthly: u.monthly, Total: u.total}
+}
+
+// Subscription usage as of the last Reconcile, nil before the first one
+func (q *QuotaTracker) Account() *AccountUsage {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.account == nil {
+		return nil
+	}
+	a := *q.account
+	return &a
+}
+
+// Refresh the account usage from User(). Characters the server billed beyond what the tracker
+// recorded since the previous call are reported as Untracked, e.g. usage by other integrations.
+func (q *QuotaTracker) Reconcile(c *Client) error {
+	if q == nil {
+		return nil
+	}
+	// Characters recorded while User() is in flight may not be counted by it yet, they stay tracked
+	q.mu.Lock()
+	tracked := q.tracked
+	q.mu.Unlock()
+
+	u, err := c.User()
+	if err != nil {
+		return fmt.Errorf("reconciling quota: %w", err)
+	}
+	sub := u.Subscription
+
+	q.mu.Lock()
+	a := &AccountUsage{
+		CharacterCount: sub.CharacterCount,
+		CharacterLimit: sub.CharacterLimit,
+		CanExtend:      sub.CanExtendCharacterLimit,
+		At:             q.now(),
+	}
+	// A lower count means the subscription period was reset
+	if prev := q.account; prev != nil && sub.CharacterCount >= prev.CharacterCount {
+		a.Untracked = max(sub.CharacterCount-prev.CharacterCount-tracked, 0)
+	} else {
+		clear(q.accountWarned)
+	}
+	q.account = a
+	q.tracked -= tracked
+	q.mu.Unlock()
+	return nil
+}
+
+// Reconcile every interval until ctx is done, failures are retried at the next tick
+func (q *QuotaTracker) ReconcileEvery(ctx context.Context, c *Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := q.Reconcile(c.WithContext(ctx)); err != nil {
+			c.opts.log().Warn("quota reconcile failed", "error", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
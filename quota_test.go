@@ -0,0 +1,151 @@
+package elevenlabs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+func TestQuotaWarnings(t *testing.T) {
+	var warnings []elevenlabs.QuotaWarning
+	q := elevenlabs.NewQuotaTracker(
+		elevenlabs.WithBudget("acme", elevenlabs.Budget{DailySoft: 30, DailyHard: 100}),
+		elevenlabs.WithQuotaWarning(func(w elevenlabs.QuotaWarning) { warnings = append(warnings, w) }),
+	)
+
+	q.Add("acme", 50)
+	q.Add("acme", 40)
+	q.Add("acme", 5)
+	q.Add("other", 500) // Default budget has no limits
+
+	want := []elevenlabs.QuotaWarning{
+		{Tenant: "acme", Period: elevenlabs.QuotaDaily, Used: 50, Limit: 30, Soft: true},
+		{Tenant: "acme", Period: elevenlabs.QuotaDaily, Used: 50, Limit: 100, Threshold: 0.5},
+		{Tenant: "acme", Period: elevenlabs.QuotaDaily, Used: 90, Limit: 100, Threshold: 0.8},
+		{Tenant: "acme", Period: elevenlabs.QuotaDaily, Used: 90, Limit: 100, Threshold: 0.9},
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("warnings = %+v", warnings)
+	}
+	for i := range want {
+		if warnings[i] != want[i] {
+			t.Errorf("warning %d = %+v, want %+v", i, warnings[i], want[i])
+		}
+	}
+
+	if u := q.Usage("acme"); u.Daily != 95 || u.Monthly != 95 || u.Total != 95 {
+		t.Errorf("Usage = %+v", u)
+	}
+	if err := q.Check("acme"); err != nil {
+		t.Errorf("Check below the hard limit = %v", err)
+	}
+	q.Add("acme", 5)
+	if err := q.Check("acme"); !errors.Is(err, elevenlabs.ErrBudgetExceeded) {
+		t.Errorf("Check at the hard limit = %v", err)
+	}
+	if err := q.Check("other"); err != nil {
+		t.Errorf("Check of another tenant = %v", err)
+	}
+}
+
+func TestQuotaNilTracker(t *testing.T) {
+	var q *elevenlabs.QuotaTracker
+	q.Add("acme", 10)
+	if err := q.Check("acme"); err != nil {
+		t.Errorf("nil tracker Check = %v", err)
+	}
+	if u := q.Usage("acme"); u != (elevenlabs.QuotaUsage{Tenant: "acme"}) {
+		t.Errorf("nil tracker Usage = %+v", u)
+	}
+	if a := q.Account(); a != nil {
+		t.Errorf("nil tracker Account = %+v", a)
+	}
+	if err := q.Reconcile(nil); err != nil {
+		t.Errorf("nil tracker Reconcile = %v", err)
+	}
+}
+
+func TestQuotaBlocksStreamingBeforeDialing(t *testing.T) {
+	srv := newServer(t)
+	q := elevenlabs.NewQuotaTracker(elevenlabs.WithDefaultBudget(elevenlabs.Budget{MonthlyHard: 10}))
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithWebsocketBaseURL(srv.WebsocketURL()),
+		elevenlabs.WithQuota(q), elevenlabs.WithTenant("acme"))
+
+	run := func(c *elevenlabs.Client, text string) error {
+		ch := make(chan string, 2)
+		ch <- text
+		ch <- elevenlabs.CLOSURE_MARKER
+		return c.StreamingRequest(ch, nil, io.Discard, "voice", "model", elevenlabs.TextToSpeechInputStreamingRequest{})
+	}
+
+	if err := run(c, "Hello there."); err != nil {
+		t.Fatal(err)
+	}
+	if u := q.Usage("acme"); u.Monthly != 12 {
+		t.Errorf("monthly usage = %d, want 12", u.Monthly)
+	}
+	if err := run(c, "Again"); !errors.Is(err, elevenlabs.ErrBudgetExceeded) {
+		t.Errorf("StreamingRequest over budget = %v", err)
+	}
+	if n := srv.Connections(); n != 1 {
+		t.Errorf("connections = %d, the rejected request was dialed", n)
+	}
+
+	// Other tenants of the same client keep their own budget
+	if err := run(c.ForTenant("other"), "abc"); err != nil {
+		t.Errorf("StreamingRequest for another tenant = %v", err)
+	}
+	if u := q.Usage("other"); u.Total != 3 {
+		t.Errorf("other usage = %+v", u)
+	}
+}
+
+func TestQuotaReconcile(t *testing.T) {
+	var count atomic.Int64
+	count.Store(100)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"subscription":{"character_count":%d,"character_limit":130}}`, count.Load())
+	}))
+	defer api.Close()
+	c := elevenlabs.NewClient(context.Background(), "key", time.Second, elevenlabs.WithBaseURL(api.URL))
+
+	var warnings []elevenlabs.QuotaWarning
+	q := elevenlabs.NewQuotaTracker(elevenlabs.WithQuotaWarning(func(w elevenlabs.QuotaWarning) { warnings = append(warnings, w) }))
+	if q.Account() != nil {
+		t.Error("Account before the first Reconcile")
+	}
+	if err := q.Reconcile(c); err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 characters sent through the tracker, 40 by something else
+	q.Add("acme", 10)
+	count.Store(150)
+	if err := q.Reconcile(c); err != nil {
+		t.Fatal(err)
+	}
+	a := q.Account()
+	if a == nil || a.CharacterCount != 150 || a.CharacterLimit != 130 || a.Untracked != 40 {
+		t.Fatalf("Account = %+v", a)
+	}
+	if err := q.Check("acme"); !errors.Is(err, elevenlabs.ErrBudgetExceeded) {
+		t.Errorf("Check over the account limit = %v", err)
+	}
+
+	// 110 of 130 account characters once the tracked ones were added
+	if len(warnings) != 2 || warnings[0].Period != elevenlabs.QuotaAccount || warnings[0].Used != 110 || warnings[1].Threshold != 0.8 {
+		t.Errorf("warnings = %+v", warnings)
+	}
+}
@@ -0,0 +1,177 @@
+package elevenlabs
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+// Opt-in redial behaviour for StreamingRequest
+type ReconnectPolicy struct {
+	MaxAttempts    int                  // Redials per failure before giving up, default 5
+	InitialBackoff time.Duration        // Delay before the first redial, default 100ms
+	MaxBackoff     time.Duration        // Backoff cap, default 5s
+	OnReconnect    func(ReconnectEvent) // Called after every redial attempt
+}
+
+type ReconnectEvent struct {
+	Attempt  int           // 1-based attempt for the current failure
+	Cause    error         // Error that dropped the connection
+	Err      error         // Attempt failure, nil once reconnected
+	Backoff  time.Duration // Delay waited before the attempt
+	Replayed int           // Frames re-sent after reconnecting
+}
+
+func WithReconnect(policy ReconnectPolicy) Option {
+	return func(o *options) {
+		if policy.MaxAttempts <= 0 {
+			policy.MaxAttempts = 5
+		}
+		if policy.InitialBackoff <= 0 {
+			policy.InitialBackoff = 100 * time.Millisecond
+		}
+		if policy.MaxBackoff <= 0 {
+			policy.MaxBackoff = 5 * time.Second
+		}
+		o.reconnect = &policy
+	}
+}
+
+// Transient transport failures, as opposed to server decisions or bad payloads
+func reconnectable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable
+	}
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		switch closeErr.Code {
+		case websocket.CloseAbnormalClosure, websocket.CloseGoingAway, websocket.CloseServiceRestart,
+			websocket.CloseTryAgainLater, websocket.CloseInternalServerErr:
+			return true
+		}
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Frames whose audio has not been received yet, replayed after a reconnect. Text is acknowledged
+// by the alignment characters the server returns for it, so replays never repeat delivered audio.
+// Without alignment, audio acknowledges the text sent up to the first flush.
+type replayBuffer struct {
+	mu      sync.Mutex
+	frames  []TextToSpeechInputMultiStreamingRequest
+	voice   *VoiceSettings // Set by an acknowledged frame and still in effect for the remaining ones
+	merged  bool           // voice came from overrides
+	partial string         // Acknowledged start of the word the pending text resumes, replayed with it
+	aligned bool           // The server returns alignment, audio alone acknowledges nothing
+}
+
+func (b *replayBuffer) add(frame TextToSpeechInputMultiStreamingRequest) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.frames = append(b.frames, frame)
+}
+
+// Server frame received carrying alignment for chars characters of the sent text, and audio or not,
+// true when it completes the audio of a flush
+func (b *replayBuffer) ack(isFinal bool, chars int, audio bool) (flushed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if isFinal {
+		b.frames, b.voice, b.merged, b.partial = nil, nil, false, ""
+		return false
+	}
+	if chars > 0 {
+		b.aligned = true
+	} else if audio && !b.aligned {
+		chars = b.unflushed()
+	}
+
+	for len(b.frames) > 0 {
+		f := &b.frames[0]
+		switch {
+		case strings.TrimSpace(f.Text) != "":
+			runes := []rune(f.Text)
+			if chars < len(runes) {
+				if chars > 0 {
+					b.partial = lastWord(b.partial + string(runes[:chars]))
+					f.Text = string(runes[chars:])
+				}
+				return flushed
+			}
+			chars -= len(runes)
+			b.partial = lastWord(b.partial + f.Text)
+		case f.Flush:
+			flushed = true
+			b.partial = "" // Generated as it was, even mid-word
+		case f.Text != "":
+			b.partial = "" // Keep-alive, ends the word
+		case f.CloseContext || f.CloseSocket:
+			return flushed
+		}
+		// Flushes and keep-alives only cover acknowledged text
+		if f.VoiceSettings != nil {
+			b.voice, b.merged = f.VoiceSettings, f.mergedVoice
+		}
+		b.frames = b.frames[1:]
+	}
+	return flushed
+}
+
+// Characters of the text sent before the first pending flush
+func (b *replayBuffer) unflushed() int {
+	var n int
+	for _, f := range b.frames {
+		if f.Flush {
+			return n
+		}
+		if f.CloseContext || f.CloseSocket {
+			break
+		}
+		if strings.TrimSpace(f.Text) != "" {
+			n += len([]rune(f.Text))
+		}
+	}
+	return n
+}
+
+// Text after the last whitespace, empty when s ends on a word boundary
+func lastWord(s string) string {
+	if i := strings.LastIndexFunc(s, unicode.IsSpace); i >= 0 {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		return s[i+size:]
+	}
+	return s
+}
+
+func (b *replayBuffer) pending() []TextToSpeechInputMultiStreamingRequest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	frames := append([]TextToSpeechInputMultiStreamingRequest(nil), b.frames...)
+	partial := b.partial
+	for i := range frames {
+		if frames[i].Text != "" && strings.TrimSpace(frames[i].Text) == "" {
+			partial = "" // A keep-alive ends the word
+		}
+		if strings.TrimSpace(frames[i].Text) != "" {
+			if frames[i].VoiceSettings == nil {
+				frames[i].VoiceSettings, frames[i].mergedVoice = b.voice, b.merged
+			}
+			// Never resume mid-word, the whole word is generated again
+			if r, _ := utf8.DecodeRuneInString(frames[i].Text); !unicode.IsSpace(r) {
+				frames[i].Text = partial + frames[i].Text
+			}
+			break
+		}
+	}
+	return frames
+}
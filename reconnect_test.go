@@ -0,0 +1,154 @@
+package elevenlabs
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func texts(frames []TextToSpeechInputMultiStreamingRequest) []string {
+	var out []string
+	for _, f := range frames {
+		switch {
+		case f.Flush:
+			out = append(out, "<flush>")
+		case f.CloseContext:
+			out = append(out, "<close>")
+		default:
+			out = append(out, f.Text)
+		}
+	}
+	return out
+}
+
+func TestReplayBufferAck(t *testing.T) {
+	var b replayBuffer
+	voice := &VoiceSettings{Stability: 0.2}
+	b.add(TextToSpeechInputMultiStreamingRequest{Text: "héllo ", VoiceSettings: voice})
+	b.add(TextToSpeechInputMultiStreamingRequest{Flush: true})
+	b.add(TextToSpeechInputMultiStreamingRequest{Text: " "}) // Keep-alive
+	b.add(TextToSpeechInputMultiStreamingRequest{Text: "world"})
+	b.add(TextToSpeechInputMultiStreamingRequest{CloseContext: true})
+
+	// Part of the first chunk, by characters rather than bytes, the word is replayed whole
+	if b.ack(false, 2, true) {
+		t.Error("flush reported before its text was acknowledged")
+	}
+	if got := texts(b.pending()); len(got) != 5 || got[0] != "héllo " {
+		t.Fatalf("pending = %q", got)
+	}
+
+	// The rest of it completes the flush, the keep-alive before the next text goes with it
+	if !b.ack(false, 4, true) {
+		t.Error("flush not reported")
+	}
+	pending := b.pending()
+	if got := texts(pending); len(got) != 2 || got[0] != "world" || got[1] != "<close>" {
+		t.Fatalf("pending = %q", got)
+	}
+	// Settings sent with acknowledged text still apply to the replayed text
+	if pending[0].VoiceSettings != voice {
+		t.Errorf("replayed voice settings = %+v", pending[0].VoiceSettings)
+	}
+
+	// Closures are only acknowledged by isFinal
+	b.ack(false, 10, true)
+	if got := texts(b.pending()); len(got) != 1 || got[0] != "<close>" {
+		t.Fatalf("pending = %q", got)
+	}
+	b.ack(true, 0, false)
+	if got := b.pending(); len(got) != 0 {
+		t.Errorf("pending after isFinal = %+v", got)
+	}
+}
+
+func TestReplayBufferWordBoundaries(t *testing.T) {
+	var b replayBuffer
+	b.add(TextToSpeechInputMultiStreamingRequest{Text: "hel"})
+	b.add(TextToSpeechInputMultiStreamingRequest{Text: "lo wor"})
+	b.add(TextToSpeechInputMultiStreamingRequest{Text: "ld"})
+
+	// Cut on a boundary, nothing to repeat
+	b.ack(false, 5, true)
+	if got := texts(b.pending()); len(got) != 2 || got[0] != " wor" {
+		t.Fatalf("pending = %q", got)
+	}
+
+	// Cut inside a word spanning frames, the acknowledged part goes out again
+	b.ack(false, 3, true)
+	if got := texts(b.pending()); len(got) != 2 || got[0] != "wor" || got[1] != "ld" {
+		t.Fatalf("pending = %q", got)
+	}
+	b.ack(false, 1, true)
+	if got := texts(b.pending()); len(got) != 1 || got[0] != "world" {
+		t.Fatalf("pending = %q", got)
+	}
+
+	// A keep-alive ends the word
+	b.ack(false, 2, true)
+	b.add(TextToSpeechInputMultiStreamingRequest{Text: " "})
+	b.add(TextToSpeechInputMultiStreamingRequest{Text: "again"})
+	if got := texts(b.pending()); len(got) != 2 || got[1] != "again" {
+		t.Errorf("pending = %q", got)
+	}
+}
+
+func TestReplayBufferAckWithoutAlignment(t *testing.T) {
+	var b replayBuffer
+	b.add(TextToSpeechInputMultiStreamingRequest{Text: "one tw"})
+	b.add(TextToSpeechInputMultiStreamingRequest{Flush: true})
+	b.add(TextToSpeechInputMultiStreamingRequest{Text: "o thr"})
+
+	// Frames without audio acknowledge nothing
+	if b.ack(false, 0, false); len(b.pending()) != 3 {
+		t.Fatalf("pending = %q", texts(b.pending()))
+	}
+	// Audio covers the text up to the flush, which generated the partial word as it was
+	if !b.ack(false, 0, true) {
+		t.Error("flush not reported")
+	}
+	if got := texts(b.pending()); len(got) != 1 || got[0] != "o thr" {
+		t.Fatalf("pending = %q", got)
+	}
+
+	// Without a flush the server holds back the last word, it is replayed whole
+	b.ack(false, 0, true)
+	b.add(TextToSpeechInputMultiStreamingRequest{Text: "ee"})
+	if got := texts(b.pending()); len(got) != 1 || got[0] != "three" {
+		t.Fatalf("pending = %q", got)
+	}
+	b.ack(true, 0, false)
+
+	// Once the server sends alignment, audio alone no longer counts
+	b.add(TextToSpeechInputMultiStreamingRequest{Text: "four five"})
+	b.ack(false, 5, true)
+	b.ack(false, 0, true)
+	if got := texts(b.pending()); len(got) != 1 || got[0] != "five" {
+		t.Errorf("pending = %q", got)
+	}
+}
+
+func TestReconnectable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{io.EOF, true},
+		{io.ErrUnexpectedEOF, true},
+		{&net.OpError{Op: "read", Err: errors.New("reset")}, true},
+		{&websocket.CloseError{Code: websocket.CloseAbnormalClosure}, true},
+		{&websocket.CloseError{Code: websocket.CloseTryAgainLater}, true},
+		{&websocket.CloseError{Code: websocket.ClosePolicyViolation}, false},
+		{ErrSystemBusy, true},
+		{newAPIError("quota_exceeded", "", 0), false},
+		{errors.New("decoding audio"), false},
+	}
+	for _, tt := range tests {
+		if got := reconnectable(tt.err); got != tt.want {
+			t.Errorf("reconnectable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package elevenlabs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error bodies are read up to this size
+const MAX_ERROR_BODY = 64 << 10
+
+// Retry behaviour of REST requests. 429 responses and failed dials are always retried, 5xx responses
+// and other transport failures only for idempotent requests.
+type RetryPolicy struct {
+	MaxAttempts    int           // Attempts including the first, default 3, 1 disables retries
+	InitialBackoff time.Duration // Delay before the first retry, default 250ms
+	MaxBackoff     time.Duration // Backoff cap, default 10s. Retry-After is honored even when longer.
+}
+
+var DEFAULT_RETRY_POLICY = RetryPolicy{MaxAttempts: 3, InitialBackoff: 250 * time.Millisecond, MaxBackoff: 10 * time.Second}
+
+func WithRetry(policy RetryPolicy) Option {
+	return func(o *options) {
+		if policy.MaxAttempts <= 0 {
+			policy.MaxAttempts = DEFAULT_RETRY_POLICY.MaxAttempts
+		}
+		if policy.InitialBackoff <= 0 {
+			policy.InitialBackoff = DEFAULT_RETRY_POLICY.InitialBackoff
+		}
+		if policy.MaxBackoff <= 0 {
+			policy.MaxBackoff = DEFAULT_RETRY_POLICY.MaxBackoff
+		}
+		o.retry = policy
+	}
+}
+
+// Response headers of a REST call
+type ResponseMeta struct {
+	Method        string
+	Path          string
+	StatusCode    int
+	Attempts      int
+	RequestID     string // request-id
+	CharacterCost int    // character-cost, -1 when not reported
+	HistoryItemID string // history-item-id
+	Header        http.Header
+}
+
+// Called after every REST call, successful or not, e.g. to record character costs
+func WithResponseHook(fn func(ResponseMeta)) Option {
+	return func(o *options) {
+		o.responseHook = fn
+	}
+}
+
+func newResponseMeta(method string, path string, resp *http.Response, attempts int) *ResponseMeta {
+	meta := &ResponseMeta{
+		Method:        method,
+		Path:          path,
+		StatusCode:    resp.StatusCode,
+		Attempts:      attempts,
+		RequestID:     resp.Header.Get("request-id"),
+		CharacterCost: -1,
+		HistoryItemID: resp.Header.Get("history-item-id"),
+		Header:        resp.Header,
+	}
+	if cost, err := strconv.Atoi(resp.Header.Get("character-cost")); err == nil {
+		meta.CharacterCost = cost
+	}
+	return meta
+}
+
+type restRequest struct {
+	method      string
+	path        string // Relative to the REST base URL, query included
+	contentType string
+	body        []byte
+	idempotent  bool // 5xx and transport failures are retried
+	stream      bool // The request timeout only covers the response headers
+}
+
+func newRestRequest(method string, path string) restRequest {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return restRequest{method: method, path: path, idempotent: true}
+	}
+	return restRequest{method: method, path: path}
+}
+
+// Send a REST request with a JSON body and decode the JSON response into out, in and out may be nil
+func (c *Client) Request(ctx context.Context, method string, path string, in any, out any) (*ResponseMeta, error) {
+	r := newRestRequest(method, path)
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("encoding request: %w", err)
+		}
+		r.body, r.contentType = b, JSON_CONTENT_TYPE
+	}
+	return c.sendJSON(ctx, r, out)
+}
+
+// Request bound to the client context
+func (c *Client) doJSON(method string, path string, in any, out any) error {
+	_, err := c.Request(c.ctx, method, path, in, out)
+	return err
+}
+
+func (c *Client) sendJSON(ctx context.Context, r restRequest, out any) (*ResponseMeta, error) {
+	resp, meta, err := c.send(ctx, r)
+	if err != nil {
+		return meta, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return meta, err
+	}
+	if out == nil || len(body) == 0 {
+		return meta, nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return meta, fmt.Errorf("decoding response: %w", err)
+	}
+	return meta, nil
+}
+
+// Send with retries, the returned response has a 2xx status and its body must be closed
+func (c *Client) send(ctx context.Context, r restRequest) (*http.Response, *ResponseMeta, error) {
+	policy := c.opts.retry
+	backoff := policy.InitialBackoff
+	log := c.opts.log()
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.attempt(ctx, r)
+
+		var meta *ResponseMeta
+		var wait time.Duration
+		retry := false
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, nil, err
+			}
+			retry = dialFailed(err) || (r.idempotent && reconnectable(err))
+		default:
+			meta = newResponseMeta(r.method, r.path, resp, attempt)
+			if c.opts.responseHook != nil {
+				c.opts.responseHook(*meta)
+			}
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return resp, meta, nil
+			}
+
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, MAX_ERROR_BODY))
+			resp.Body.Close()
+			err = decodeAPIError(resp.StatusCode, body, meta.RequestID)
+			retry = resp.StatusCode == http.StatusTooManyRequests || (r.idempotent && resp.StatusCode >= 500)
+			wait = retryAfter(resp.Header.Get("Retry-After"))
+		}
+
+		if !retry || attempt >= policy.MaxAttempts {
+			return nil, meta, err
+		}
+		if wait == 0 {
+			// Equal jitter keeps at least half of the backoff
+			wait = backoff/2 + rand.N(backoff/2+1)
+			backoff = min(backoff*2, policy.MaxBackoff)
+		}
+		log.Warn("retrying REST request", "method", r.method, "path", r.path, "attempt", attempt, "wait", wait, "error", err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, meta, ctx.Err()
+		}
+	}
+}
+
+// One HTTP round trip bounded by the request timeout
+func (c *Client) attempt(ctx context.Context, r restRequest) (*http.Response, error) {
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(ctx, r.method, c.opts.baseURLHTTPS+r.path, body)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("xi-api-key", c.apiKey)
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+
+	var timer *time.Timer
+	if c.timeout > 0 {
+		timer = time.AfterFunc(c.timeout, cancel)
+	}
+	resp, err := c.opts.restClient().Do(req)
+	if err != nil {
+		stopTimer(timer)
+		cancel()
+		return nil, err
+	}
+	if r.stream {
+		stopTimer(timer)
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, timer: timer, cancel: cancel}
+	return resp, nil
+}
+
+// Releases the attempt context once the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	stopTimer(b.timer)
+	b.cancel()
+	return err
+}
+
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
+
+// The request never reached the server
+func dialFailed(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// Seconds or an HTTP date, 0 when absent or unparsable
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// APIError from an error body: {"detail": {"status", "message"}}, {"detail": "message"} or validation errors
+func decodeAPIError(status int, body []byte, requestID string) *APIError {
+	var envelope struct {
+		Detail json.RawMessage `json:"detail"`
+	}
+	var code, message string
+	if json.Unmarshal(body, &envelope) == nil && len(envelope.Detail) > 0 {
+		var detail struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		}
+		var text string
+		var validation []struct {
+			Loc []any  `json:"loc"`
+			Msg string `json:"msg"`
+		}
+		switch {
+		case json.Unmarshal(envelope.Detail, &detail) == nil:
+			code, message = detail.Status, detail.Message
+		case json.Unmarshal(envelope.Detail, &text) == nil:
+			message = text
+		case json.Unmarshal(envelope.Detail, &validation) == nil:
+			code = ErrInvalidRequest.Code
+			var msgs []string
+			for _, v := range validation {
+				msgs = append(msgs, fmt.Sprintf("%v: %s", v.Loc, v.Msg))
+			}
+			message = strings.Join(msgs, "; ")
+		}
+	}
+	if code == "" && message == "" {
+		message = strings.TrimSpace(string(body))
+		if message == "" {
+			message = http.StatusText(status)
+		}
+	}
+
+	e := newAPIError(code, message, status)
+	e.RequestID = requestID
+	return e
+}
@@ -0,0 +1,231 @@
+package elevenlabs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+// REST server answering with the scripted handlers in order, the last one repeats
+func scripted(t *testing.T, handlers ...http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		handlers[min(n, len(handlers))-1](w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func status(code int, headers ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.WriteHeader(code)
+	}
+}
+
+func body(code int, s string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+		io.WriteString(w, s)
+	}
+}
+
+func restClient(t *testing.T, url string, opts ...elevenlabs.Option) *elevenlabs.Client {
+	opts = append([]elevenlabs.Option{
+		elevenlabs.WithBaseURL(url),
+		elevenlabs.WithRetry(elevenlabs.RetryPolicy{InitialBackoff: time.Millisecond}),
+	}, opts...)
+	return elevenlabs.NewClient(context.Background(), "key", time.Second, opts...)
+}
+
+func TestRequestRetriesIdempotentFailures(t *testing.T) {
+	srv, calls := scripted(t,
+		status(http.StatusTooManyRequests, "Retry-After", "0"),
+		status(http.StatusServiceUnavailable),
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("xi-api-key") != "key" {
+				t.Errorf("api key header = %q", r.Header.Get("xi-api-key"))
+			}
+			w.Header().Set("request-id", "req-1")
+			w.Header().Set("character-cost", "12")
+			w.Header().Set("history-item-id", "hist-1")
+			io.WriteString(w, `{"user_id":"u"}`)
+		},
+	)
+	var mu sync.Mutex
+	var hooked []elevenlabs.ResponseMeta
+	c := restClient(t, srv.URL, elevenlabs.WithResponseHook(func(m elevenlabs.ResponseMeta) {
+		mu.Lock()
+		hooked = append(hooked, m)
+		mu.Unlock()
+	}))
+
+	var out struct {
+		UserID string `json:"user_id"`
+	}
+	meta, err := c.Request(context.Background(), http.MethodGet, "/user", nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.UserID != "u" || calls.Load() != 3 {
+		t.Errorf("user %q after %d calls", out.UserID, calls.Load())
+	}
+	if meta.Attempts != 3 || meta.StatusCode != 200 || meta.RequestID != "req-1" || meta.CharacterCost != 12 || meta.HistoryItemID != "hist-1" {
+		t.Errorf("meta = %+v", meta)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hooked) != 3 || hooked[0].StatusCode != 429 || hooked[1].StatusCode != 503 {
+		t.Errorf("hook saw %+v", hooked)
+	}
+}
+
+func TestRequestDoesNotRetryPostServerErrors(t *testing.T) {
+	srv, calls := scripted(t, status(http.StatusInternalServerError))
+	_, err := restClient(t, srv.URL).Request(context.Background(), http.MethodPost, "/post", map[string]int{}, nil)
+
+	var apiErr *elevenlabs.APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusInternalServerError || !apiErr.Retryable {
+		t.Fatalf("Request = %v, want a retryable 500", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("POST sent %d times", n)
+	}
+
+	// Rate limits are rejected before any work is done, they are retried for every method
+	srv, calls = scripted(t, status(http.StatusTooManyRequests), status(http.StatusOK))
+	if _, err := restClient(t, srv.URL).Request(context.Background(), http.MethodPost, "/post", nil, nil); err != nil {
+		t.Errorf("POST after a 429 = %v", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("POST sent %d times", n)
+	}
+}
+
+func TestRequestHonorsRetryAfter(t *testing.T) {
+	srv, _ := scripted(t, status(http.StatusTooManyRequests, "Retry-After", "1"), status(http.StatusOK))
+	start := time.Now()
+	if _, err := restClient(t, srv.URL).Request(context.Background(), http.MethodGet, "/user", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, Retry-After asked for 1s", elapsed)
+	}
+}
+
+// Transport failing every round trip with err
+type failingTransport struct {
+	err   error
+	calls atomic.Int32
+}
+
+func (f *failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	f.calls.Add(1)
+	return nil, f.err
+}
+
+func TestRequestTransportFailures(t *testing.T) {
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	read := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+	tests := []struct {
+		name   string
+		method string
+		err    error
+		calls  int32
+	}{
+		{"dial post", http.MethodPost, dial, 3},
+		{"read post", http.MethodPost, read, 1},
+		{"read get", http.MethodGet, read, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &failingTransport{err: tt.err}
+			c := restClient(t, "http://api.invalid", elevenlabs.WithHTTPClient(&http.Client{Transport: transport}))
+			if _, err := c.Request(context.Background(), tt.method, "/x", nil, nil); err == nil {
+				t.Fatal("Request succeeded")
+			}
+			if n := transport.calls.Load(); n != tt.calls {
+				t.Errorf("%d attempts, want %d", n, tt.calls)
+			}
+		})
+	}
+}
+
+func TestRequestErrorBodies(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		code    string
+		message string
+		status  int
+	}{
+		{"detail object", body(401, `{"detail":{"status":"invalid_api_key","message":"bad key"}}`), "invalid_api_key", "bad key", 401},
+		{"detail alias", body(400, `{"detail":{"status":"voice_does_not_exist","message":"no"}}`), "voice_not_found", "no", 400},
+		{"detail string", body(404, `{"detail":"not here"}`), "", "not here", 404},
+		{"validation", body(422, `{"detail":[{"loc":["body","text"],"msg":"field required"}]}`), "invalid_request", "[body text]: field required", 422},
+		{"plain text", body(418, "teapot\n"), "", "teapot", 418},
+		{"empty", body(403, ""), "", "Forbidden", 403},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := scripted(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("request-id", "req-9")
+				tt.handler(w, r)
+			})
+			_, err := restClient(t, srv.URL).Request(context.Background(), http.MethodGet, "/x", nil, nil)
+
+			var apiErr *elevenlabs.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Request = %v, want an APIError", err)
+			}
+			if apiErr.Code != tt.code || apiErr.Message != tt.message || apiErr.Status != tt.status || apiErr.RequestID != "req-9" {
+				t.Errorf("APIError = %+v", apiErr)
+			}
+		})
+	}
+}
+
+func TestRequestCancelled(t *testing.T) {
+	srv, calls := scripted(t, status(http.StatusServiceUnavailable))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := restClient(t, srv.URL).Request(ctx, http.MethodGet, "/x", nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Request = %v, want context.Canceled", err)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("%d calls after cancel", n)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv, _ := scripted(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	c := elevenlabs.NewClient(context.Background(), "key", 50*time.Millisecond, elevenlabs.WithBaseURL(srv.URL),
+		elevenlabs.WithRetry(elevenlabs.RetryPolicy{MaxAttempts: 1}))
+
+	start := time.Now()
+	if _, err := c.Request(context.Background(), http.MethodGet, "/x", nil, nil); err == nil {
+		t.Fatal("Request succeeded past its timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("timed out after %v", elapsed)
+	}
+}
@@ -0,0 +1,277 @@
+// Package rtp sends streaming TTS audio as RTP packets, one per 20 ms frame.
+package rtp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+const HEADER_SIZE = 12
+const FRAME_DURATION = 20 * time.Millisecond
+
+// Static payload types from RFC 3551
+const (
+	PAYLOAD_PCMU = 0
+	PAYLOAD_PCMA = 8
+	PAYLOAD_L16  = 96 // Dynamic, has to match the SDP
+)
+
+type Option func(*Packetizer)
+
+func WithPayloadType(pt uint8) Option {
+	return func(p *Packetizer) {
+		p.payloadType = pt
+		p.fixedPT = true
+	}
+}
+
+func WithSSRC(ssrc uint32) Option {
+	return func(p *Packetizer) {
+		p.ssrc = ssrc
+	}
+}
+
+// First sequence number and timestamp, random by default
+func WithInitialState(seq uint16, timestamp uint32) Option {
+	return func(p *Packetizer) {
+		p.seq = seq
+		p.ts = timestamp
+	}
+}
+
+// Send packets at real-time cadence, Write blocks until its frames are due
+func WithPacing(paced bool) Option {
+	return func(p *Packetizer) {
+		p.paced = paced
+	}
+}
+
+// io.Writer slicing μ-law, A-law or PCM16 audio into RTP packets sent to addr.
+// Use it as AudioResponsePipe, the streaming request reports the format through SetAudioFormat
+// and marks generation ends through AudioBoundary.
+type Packetizer struct {
+	conn net.PacketConn
+	addr net.Addr
+
+	mu          sync.Mutex
+	payloadType uint8
+	fixedPT     bool
+	ssrc        uint32
+	seq         uint16
+	ts          uint32
+	paced       bool
+	format      elevenlabs.AudioFormatInfo
+	err         error // Unsupported or missing format
+
+	frameSize int    // Payload bytes per frame
+	samples   uint32 // Timestamp increment per frame
+	silence   byte   // Padding for the last frame of a talkspurt
+	pending   []byte // Partial frame
+	marker    bool   // Next packet starts a talkspurt
+	next      time.Time
+	packet    []byte
+
+	stop      chan struct{} // Closed by Close, ends a paced wait
+	closeOnce sync.Once
+	closed    bool
+}
+
+// Packetizer sending to addr over conn, the caller keeps ownership of conn
+func NewPacketizer(conn net.PacketConn, addr net.Addr, opts ...Option) *Packetizer {
+	p := &Packetizer{
+		conn:   conn,
+		addr:   addr,
+		ssrc:   rand.Uint32(),
+		seq:    uint16(rand.Uint32()),
+		ts:     rand.Uint32(),
+		marker: true,
+		err:    fmt.Errorf("audio format not set"),
+		stop:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Audio format of the payload, G.711 or PCM16 mono
+func (p *Packetizer) SetAudioFormat(info elevenlabs.AudioFormatInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.err = nil
+	p.format = info
+	p.pending = p.pending[:0]
+	p.samples = uint32(info.SampleRate / int(time.Second/FRAME_DURATION))
+	p.frameSize = int(p.samples) * info.FrameSize()
+
+	var pt uint8
+	switch {
+	case info.Channels != 1:
+		p.err = fmt.Errorf("unsupported channel count %d", info.Channels)
+	case info.Encoding == elevenlabs.EncodingULAW:
+		pt, p.silence = PAYLOAD_PCMU, 0xFF
+	case info.Encoding == elevenlabs.EncodingALAW:
+		pt, p.silence = PAYLOAD_PCMA, 0xD5
+	case info.Encoding == elevenlabs.EncodingPCM:
+		pt, p.silence = PAYLOAD_L16, 0
+	default:
+		p.err = fmt.Errorf("unsupported audio format %q", info.Format)
+	}
+	if !p.fixedPT {
+		p.payloadType = pt
+	}
+}
+
+// Finish the current talkspurt, called by the streaming request on isFinal and once a flush is played
+func (p *Packetizer) AudioBoundary() {
+	p.Mark()
+}
+
+// Send the partial frame padded with silence and start a new talkspurt with the next write
+func (p *Packetizer) Mark() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.flush()
+	p.marker = true
+	return err
+}
+
+// Send the partial frame without waiting for its slot, a paced Write in progress returns net.ErrClosed.
+// The caller keeps ownership of the connection.
+func (p *Packetizer) Close() error {
+	p.closeOnce.Do(func() { close(p.stop) })
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	return p.flush()
+}
+
+func (p *Packetizer) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return 0, net.ErrClosed
+	}
+	if p.err != nil {
+		return 0, p.err
+	}
+
+	// The lock is released while a paced send waits, frames are taken out of pending before sending
+	n, size := len(b), p.frameSize
+	if len(p.pending) > 0 {
+		take := min(size-len(p.pending), len(b))
+		p.pending = append(p.pending, b[:take]...)
+		b = b[take:]
+		if len(p.pending) < size {
+			return n, nil
+		}
+		frame := p.pending
+		p.pending = nil
+		if err := p.send(frame); err != nil {
+			return 0, err
+		}
+	}
+	for ; len(b) >= size; b = b[size:] {
+		if p.closed {
+			return 0, net.ErrClosed
+		}
+		if err := p.send(b[:size]); err != nil {
+			return 0, err
+		}
+	}
+	p.pending = append(p.pending, b...)
+	return n, nil
+}
+
+func (p *Packetizer) flush() error {
+	if p.err != nil || len(p.pending) == 0 {
+		return nil
+	}
+	frame := p.pending
+	p.pending = nil
+	for len(frame) < p.frameSize {
+		frame = append(frame, p.silence)
+	}
+	return p.send(frame)
+}
+
+// Send one frame, waiting for its slot when paced. Called with mu held, which is released during the wait.
+func (p *Packetizer) send(frame []byte) error {
+	if p.paced && !p.closed {
+		var wait time.Duration
+		now := time.Now()
+		switch {
+		case p.next.IsZero():
+			p.next = now
+		case now.Sub(p.next) > FRAME_DURATION:
+			// Underrun, the gap is silence on the receiving side
+			gap := now.Sub(p.next)
+			p.ts += uint32(gap * time.Duration(p.format.SampleRate) / time.Second)
+			p.next = now
+			p.marker = true
+		default:
+			wait = p.next.Sub(now)
+		}
+		// The slot is taken before Mark, Close or another Write get in during the wait
+		p.next = p.next.Add(FRAME_DURATION)
+		if wait > 0 && !p.wait(wait) {
+			return net.ErrClosed
+		}
+	}
+
+	p.packet = p.packet[:0]
+	p.packet = append(p.packet, 0x80) // Version 2, no padding, extension or CSRC
+	pt := p.payloadType & 0x7F
+	if p.marker {
+		pt |= 0x80
+	}
+	p.packet = append(p.packet, pt)
+	p.packet = binary.BigEndian.AppendUint16(p.packet, p.seq)
+	p.packet = binary.BigEndian.AppendUint32(p.packet, p.ts)
+	p.packet = binary.BigEndian.AppendUint32(p.packet, p.ssrc)
+
+	if p.format.Encoding == elevenlabs.EncodingPCM {
+		// L16 is big endian on the wire
+		for i := 0; i+1 < len(frame); i += 2 {
+			p.packet = append(p.packet, frame[i+1], frame[i])
+		}
+	} else {
+		p.packet = append(p.packet, frame...)
+	}
+
+	if _, err := p.conn.WriteTo(p.packet, p.addr); err != nil {
+		return err
+	}
+	p.seq++
+	p.ts += p.samples
+	p.marker = false
+	return nil
+}
+
+// Wait for d without holding mu, false once the packetizer is closed
+func (p *Packetizer) wait(d time.Duration) bool {
+	p.mu.Unlock()
+	defer p.mu.Lock()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-p.stop:
+		return false
+	}
+}
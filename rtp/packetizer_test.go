@@ -0,0 +1,216 @@
+package rtp_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/clearlyip/elevenlabs-go-websockets/g711"
+	"github.com/clearlyip/elevenlabs-go-websockets/rtp"
+)
+
+type packet struct {
+	marker      bool
+	payloadType uint8
+	seq         uint16
+	ts          uint32
+	ssrc        uint32
+	payload     []byte
+}
+
+// Packetizer sending to a local UDP listener
+func listen(t *testing.T, format elevenlabs.AudioFormat, opts ...rtp.Option) (*rtp.Packetizer, net.PacketConn) {
+	t.Helper()
+	rx, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		rx.Close()
+		tx.Close()
+	})
+
+	p := rtp.NewPacketizer(tx, rx.LocalAddr(), opts...)
+	info, err := format.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetAudioFormat(info)
+	return p, rx
+}
+
+func receive(t *testing.T, rx net.PacketConn, n int) []packet {
+	t.Helper()
+	var packets []packet
+	buf := make([]byte, 2048)
+	for range n {
+		rx.SetReadDeadline(time.Now().Add(2 * time.Second))
+		size, _, err := rx.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("packet %d: %v", len(packets), err)
+		}
+		b := buf[:size]
+		if b[0] != 0x80 {
+			t.Fatalf("packet %d: first byte %#02x", len(packets), b[0])
+		}
+		packets = append(packets, packet{
+			marker:      b[1]&0x80 != 0,
+			payloadType: b[1] & 0x7F,
+			seq:         binary.BigEndian.Uint16(b[2:]),
+			ts:          binary.BigEndian.Uint32(b[4:]),
+			ssrc:        binary.BigEndian.Uint32(b[8:]),
+			payload:     append([]byte(nil), b[rtp.HEADER_SIZE:]...),
+		})
+	}
+	return packets
+}
+
+func TestPacketizerULaw(t *testing.T) {
+	p, rx := listen(t, elevenlabs.FormatULAW_8000, rtp.WithSSRC(42), rtp.WithInitialState(65535, 1000))
+
+	// Two and a half frames over uneven writes
+	audio := bytes.Repeat([]byte{0x10}, 400)
+	for _, chunk := range [][]byte{audio[:100], audio[100:330], audio[330:]} {
+		if n, err := p.Write(chunk); err != nil || n != len(chunk) {
+			t.Fatalf("Write = %d, %v", n, err)
+		}
+	}
+	p.AudioBoundary()
+	p.Write(audio[:160])
+
+	packets := receive(t, rx, 4)
+	wantSeq := []uint16{65535, 0, 1, 2}
+	wantMarker := []bool{true, false, false, true}
+	for i, pk := range packets {
+		if pk.seq != wantSeq[i] || pk.marker != wantMarker[i] || pk.ts != 1000+uint32(i)*160 {
+			t.Errorf("packet %d: seq %d, marker %v, ts %d", i, pk.seq, pk.marker, pk.ts)
+		}
+		if pk.payloadType != rtp.PAYLOAD_PCMU || pk.ssrc != 42 || len(pk.payload) != 160 {
+			t.Errorf("packet %d: payload type %d, ssrc %d, %d bytes", i, pk.payloadType, pk.ssrc, len(pk.payload))
+		}
+	}
+
+	// The partial frame is padded with μ-law silence
+	want := append(bytes.Repeat([]byte{0x10}, 80), bytes.Repeat([]byte{0xFF}, 80)...)
+	if !bytes.Equal(packets[2].payload, want) {
+		t.Errorf("padded frame = %x", packets[2].payload)
+	}
+}
+
+func TestPacketizerL16(t *testing.T) {
+	p, rx := listen(t, elevenlabs.FormatPCM_16000)
+
+	frame := make([]byte, 640) // 20 ms at 16 kHz
+	for i := 0; i < len(frame); i += 2 {
+		binary.LittleEndian.PutUint16(frame[i:], uint16(i))
+	}
+	p.Write(frame)
+
+	pk := receive(t, rx, 1)[0]
+	if pk.payloadType != rtp.PAYLOAD_L16 || len(pk.payload) != 640 {
+		t.Fatalf("payload type %d, %d bytes", pk.payloadType, len(pk.payload))
+	}
+	if got := binary.BigEndian.Uint16(pk.payload[2:]); got != 2 {
+		t.Errorf("second sample = %d, want 2 in network byte order", got)
+	}
+}
+
+func TestPacketizerRejectsCompressedAudio(t *testing.T) {
+	p, _ := listen(t, elevenlabs.FormatMP3_44100_128)
+	if _, err := p.Write([]byte{1, 2, 3}); err == nil {
+		t.Error("Write of mp3 audio succeeded")
+	}
+}
+
+func TestPacketizerPacing(t *testing.T) {
+	p, rx := listen(t, elevenlabs.FormatALAW_8000, rtp.WithPacing(true))
+
+	start := time.Now()
+	p.Write(make([]byte, 4*160))
+	// The first frame goes out at once, the others on their 20 ms slots
+	if elapsed := time.Since(start); elapsed < 3*rtp.FRAME_DURATION {
+		t.Errorf("four frames written in %v", elapsed)
+	}
+	for _, pk := range receive(t, rx, 4) {
+		if pk.payloadType != rtp.PAYLOAD_PCMA {
+			t.Errorf("payload type %d", pk.payloadType)
+		}
+	}
+}
+
+func TestPacketizerBehindG711Writer(t *testing.T) {
+	p, rx := listen(t, elevenlabs.FormatULAW_8000)
+	w, err := g711.NewWriter(p, elevenlabs.EncodingULAW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ := elevenlabs.FormatPCM_16000.Info()
+	w.SetAudioFormat(info)
+
+	// 20 ms of 16 kHz audio per frame, the boundary reaches the packetizer through the transcoder
+	frame := make([]byte, 640)
+	w.Write(frame)
+	w.AudioBoundary()
+	w.Write(frame)
+
+	packets := receive(t, rx, 2)
+	if !packets[0].marker || !packets[1].marker {
+		t.Errorf("markers = %v, %v, want a new talkspurt after the boundary", packets[0].marker, packets[1].marker)
+	}
+	for i, pk := range packets {
+		if pk.payloadType != rtp.PAYLOAD_PCMU || len(pk.payload) != 160 {
+			t.Errorf("packet %d: payload type %d, %d bytes", i, pk.payloadType, len(pk.payload))
+		}
+	}
+}
+
+func TestPacketizerCloseDuringPacing(t *testing.T) {
+	p, rx := listen(t, elevenlabs.FormatULAW_8000, rtp.WithPacing(true))
+
+	// A second of audio, the writer spends nearly all of it waiting for slots
+	written := make(chan error, 1)
+	go func() {
+		_, err := p.Write(make([]byte, 50*160+80))
+		written <- err
+	}()
+	receive(t, rx, 1)
+
+	// Marks are not held up by the paced write
+	marked := make(chan struct{})
+	go func() {
+		p.Mark()
+		close(marked)
+	}()
+	select {
+	case <-marked:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Mark blocked behind a paced Write")
+	}
+
+	start := time.Now()
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close = %v", err)
+	}
+	select {
+	case err := <-written:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Errorf("Write = %v, want net.ErrClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("paced Write still running after Close")
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Close took %v", elapsed)
+	}
+	if _, err := p.Write(make([]byte, 160)); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Write after Close = %v", err)
+	}
+}
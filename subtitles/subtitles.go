@@ -0,0 +1,237 @@
+// Package subtitles turns streaming alignment into SRT and WebVTT cues, incrementally while audio streams.
+package subtitles
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+const DEFAULT_MAX_LINE_CHARS = 42
+const DEFAULT_MAX_LINES = 2
+const DEFAULT_MAX_DURATION = 7 * time.Second
+
+type Cue struct {
+	Index int // 1-based
+	Start time.Duration
+	End   time.Duration
+	Text  string // Lines separated by \n
+}
+
+type Option func(*Segmenter)
+
+func WithMaxLineChars(n int) Option {
+	return func(s *Segmenter) {
+		s.maxLineChars = n
+	}
+}
+
+func WithMaxLines(n int) Option {
+	return func(s *Segmenter) {
+		s.maxLines = n
+	}
+}
+
+func WithMaxDuration(d time.Duration) Option {
+	return func(s *Segmenter) {
+		s.maxDuration = d
+	}
+}
+
+// Groups the words of an utterance into cues. A cue ends after sentence punctuation,
+// when the next word would not fit the line limits or when it would run longer than the max duration.
+type Segmenter struct {
+	maxLineChars int
+	maxLines     int
+	maxDuration  time.Duration
+
+	agg     *elevenlabs.AlignmentAggregator // Current utterance
+	offset  int                             // Start of the current utterance in ms
+	done    int                             // Words of the utterance already placed in a cue or pending
+	pending []elevenlabs.WordSpan
+	index   int
+}
+
+func NewSegmenter(opts ...Option) *Segmenter {
+	s := &Segmenter{
+		maxLineChars: DEFAULT_MAX_LINE_CHARS,
+		maxLines:     DEFAULT_MAX_LINES,
+		maxDuration:  DEFAULT_MAX_DURATION,
+		agg:          elevenlabs.NewAlignmentAggregator(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Cues completed by r, isFinal completes the last one
+func (s *Segmenter) AddResponse(r elevenlabs.StreamingOutputResponse) []Cue {
+	return s.AddChunk(r.Alignment, r.AudioDurationMs, r.IsFinal)
+}
+
+func (s *Segmenter) AddMultiCtxResponse(r elevenlabs.StreamingOutputMultiCtxResponse) []Cue {
+	return s.AddChunk(r.Alignment, r.AudioDurationMs, r.IsFinal)
+}
+
+// Cues completed by the next alignment segment, its audio duration unknown
+func (s *Segmenter) Add(alignment elevenlabs.StreamingAlignmentSegment, final bool) []Cue {
+	return s.AddChunk(alignment, 0, final)
+}
+
+// Cues completed by the alignment of a chunk carrying audioMs of audio
+func (s *Segmenter) AddChunk(alignment elevenlabs.StreamingAlignmentSegment, audioMs int, final bool) []Cue {
+	s.agg.AddChunk(alignment, elevenlabs.StreamingAlignmentSegment{}, audioMs)
+	words := s.agg.Words()
+	if !final && len(words) > 0 {
+		words = words[:len(words)-1] // Might continue in the next segment
+	}
+
+	s.done = min(s.done, len(words))
+
+	var cues []Cue
+	for _, w := range words[s.done:] {
+		w.StartMs += s.offset
+		w.EndMs += s.offset
+		if len(s.pending) > 0 && !s.fits(w) {
+			cues = append(cues, s.cue())
+		}
+		s.pending = append(s.pending, w)
+		if endsSentence(w.Word) {
+			cues = append(cues, s.cue())
+		}
+	}
+	s.done = len(words)
+
+	if final {
+		cues = append(cues, s.Flush()...)
+		// The next segment starts a new utterance, it must not join the last word
+		s.offset += s.agg.DurationMs()
+		s.agg.Reset()
+		s.done = 0
+	}
+	return cues
+}
+
+// Complete the pending cue
+func (s *Segmenter) Flush() []Cue {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	return []Cue{s.cue()}
+}
+
+func (s *Segmenter) fits(w elevenlabs.WordSpan) bool {
+	if time.Duration(w.EndMs-s.pending[0].StartMs)*time.Millisecond > s.maxDuration {
+		return false
+	}
+	return len(s.lines(append(s.pending[:len(s.pending):len(s.pending)], w))) <= s.maxLines
+}
+
+// Greedy line wrapping, a word longer than a line gets a line of its own
+func (s *Segmenter) lines(words []elevenlabs.WordSpan) []string {
+	var lines []string
+	var line string
+	for _, w := range words {
+		switch {
+		case line == "":
+			line = w.Word
+		case len([]rune(line))+1+len([]rune(w.Word)) <= s.maxLineChars:
+			line += " " + w.Word
+		default:
+			lines = append(lines, line)
+			line = w.Word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (s *Segmenter) cue() Cue {
+	s.index++
+	cue := Cue{
+		Index: s.index,
+		Start: time.Duration(s.pending[0].StartMs) * time.Millisecond,
+		End:   time.Duration(s.pending[len(s.pending)-1].EndMs) * time.Millisecond,
+		Text:  strings.Join(s.lines(s.pending), "\n"),
+	}
+	s.pending = nil
+	return cue
+}
+
+func endsSentence(word string) bool {
+	word = strings.TrimRight(word, "\"')]»”’")
+	return strings.HasSuffix(word, ".") || strings.HasSuffix(word, "!") || strings.HasSuffix(word, "?") ||
+		strings.HasSuffix(word, "…") || strings.HasSuffix(word, "。") || strings.HasSuffix(word, "！") || strings.HasSuffix(word, "？")
+}
+
+type Format int
+
+const (
+	SRT Format = iota
+	WebVTT
+)
+
+func timestamp(d time.Duration, sep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// Cue block including the trailing blank line
+func (f Format) FormatCue(c Cue) string {
+	sep := ","
+	if f == WebVTT {
+		sep = "."
+	}
+	return fmt.Sprintf("%d\n%s --> %s\n%s\n\n", c.Index, timestamp(c.Start, sep), timestamp(c.End, sep), c.Text)
+}
+
+// Complete file for cues
+func (f Format) Encode(w io.Writer, cues []Cue) error {
+	return (&Writer{w: w, format: f}).write(cues)
+}
+
+// Writes cues to a file as soon as they complete, for live captions
+type Writer struct {
+	w      io.Writer
+	format Format
+	seg    *Segmenter
+	header bool
+}
+
+func NewWriter(w io.Writer, format Format, opts ...Option) *Writer {
+	return &Writer{w: w, format: format, seg: NewSegmenter(opts...)}
+}
+
+func (w *Writer) AddResponse(r elevenlabs.StreamingOutputResponse) error {
+	return w.write(w.seg.AddResponse(r))
+}
+
+func (w *Writer) AddMultiCtxResponse(r elevenlabs.StreamingOutputMultiCtxResponse) error {
+	return w.write(w.seg.AddMultiCtxResponse(r))
+}
+
+// Write the pending cue, w is not closed
+func (w *Writer) Close() error {
+	return w.write(w.seg.Flush())
+}
+
+func (w *Writer) write(cues []Cue) error {
+	if !w.header && w.format == WebVTT {
+		if _, err := io.WriteString(w.w, "WEBVTT\n\n"); err != nil {
+			return err
+		}
+	}
+	w.header = true
+	for _, c := range cues {
+		if _, err := io.WriteString(w.w, w.format.FormatCue(c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package subtitles_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/clearlyip/elevenlabs-go-websockets/subtitles"
+)
+
+// Alignment for text as the server sends it, starting at 0 with 10 ms per character
+func segment(text string) elevenlabs.StreamingAlignmentSegment {
+	var s elevenlabs.StreamingAlignmentSegment
+	for i, r := range []rune(text) {
+		s.Chars = append(s.Chars, string(r))
+		s.CharStartTimesMs = append(s.CharStartTimesMs, i*10)
+		s.CharDurationsMs = append(s.CharDurationsMs, 10)
+	}
+	return s
+}
+
+func checkCues(t *testing.T, got []subtitles.Cue, want []subtitles.Cue) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("cues = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cue %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSegmenterSentences(t *testing.T) {
+	s := subtitles.NewSegmenter()
+	var cues []subtitles.Cue
+	cues = append(cues, s.Add(segment("Hi there. How a"), false)...)
+	cues = append(cues, s.Add(segment("re you?"), false)...)
+	cues = append(cues, s.Add(segment(" Fine"), true)...)
+
+	ms := time.Millisecond
+	checkCues(t, cues, []subtitles.Cue{
+		{Index: 1, Start: 0, End: 90 * ms, Text: "Hi there."},
+		{Index: 2, Start: 100 * ms, End: 220 * ms, Text: "How are you?"},
+		{Index: 3, Start: 230 * ms, End: 270 * ms, Text: "Fine"},
+	})
+}
+
+func TestSegmenterHoldsLastWordUntilFinal(t *testing.T) {
+	s := subtitles.NewSegmenter()
+	if cues := s.Add(segment("Hello"), false); len(cues) != 0 {
+		t.Errorf("cues for a word that may continue: %+v", cues)
+	}
+	if cues := s.Flush(); len(cues) != 0 {
+		t.Errorf("Flush = %+v, the last word is not placed yet", cues)
+	}
+}
+
+// Utterances after a final segment continue on the timeline and never join the previous word
+func TestSegmenterAfterFinal(t *testing.T) {
+	s := subtitles.NewSegmenter()
+	var cues []subtitles.Cue
+	cues = append(cues, s.Add(segment("Hello."), true)...)
+	if got := s.Add(segment("World"), false); len(got) != 0 {
+		t.Fatalf("cues before the word is complete: %+v", got)
+	}
+	cues = append(cues, s.Add(segment(" again"), false)...)
+	cues = append(cues, s.Add(segment(" now."), true)...)
+
+	ms := time.Millisecond
+	checkCues(t, cues, []subtitles.Cue{
+		{Index: 1, Start: 0, End: 60 * ms, Text: "Hello."},
+		{Index: 2, Start: 60 * ms, End: 220 * ms, Text: "World again now."},
+	})
+}
+
+func TestSegmenterAudioDuration(t *testing.T) {
+	s := subtitles.NewSegmenter()
+	var cues []subtitles.Cue
+	cues = append(cues, s.AddResponse(elevenlabs.StreamingOutputResponse{Alignment: segment("Hello. "), AudioDurationMs: 500})...)
+	cues = append(cues, s.AddResponse(elevenlabs.StreamingOutputResponse{Alignment: segment("Bye."), AudioDurationMs: 300, IsFinal: true})...)
+	cues = append(cues, s.AddResponse(elevenlabs.StreamingOutputResponse{Alignment: segment("Again"), IsFinal: true})...)
+
+	// Cues follow the audio, silence after the text of a chunk included
+	ms := time.Millisecond
+	checkCues(t, cues, []subtitles.Cue{
+		{Index: 1, Start: 0, End: 60 * ms, Text: "Hello."},
+		{Index: 2, Start: 500 * ms, End: 540 * ms, Text: "Bye."},
+		{Index: 3, Start: 800 * ms, End: 850 * ms, Text: "Again"},
+	})
+}
+
+func TestSegmenterLineLimits(t *testing.T) {
+	s := subtitles.NewSegmenter(subtitles.WithMaxLineChars(8), subtitles.WithMaxLines(2))
+	cues := s.Add(segment("aaa bbb ccc ddd eee"), true)
+
+	if len(cues) != 2 || cues[0].Text != "aaa bbb\nccc ddd" || cues[1].Text != "eee" {
+		t.Errorf("cues = %+v", cues)
+	}
+}
+
+func TestSegmenterMaxDuration(t *testing.T) {
+	s := subtitles.NewSegmenter(subtitles.WithMaxDuration(90 * time.Millisecond))
+	cues := s.Add(segment("one two three four"), true)
+
+	var texts []string
+	for _, c := range cues {
+		texts = append(texts, c.Text)
+		if c.End-c.Start > 90*time.Millisecond {
+			t.Errorf("cue %q lasts %v", c.Text, c.End-c.Start)
+		}
+	}
+	if len(texts) != 3 || texts[0] != "one two" || texts[1] != "three" || texts[2] != "four" {
+		t.Errorf("cues = %q", texts)
+	}
+}
+
+func TestWriterSRT(t *testing.T) {
+	var out bytes.Buffer
+	w := subtitles.NewWriter(&out, subtitles.SRT)
+	w.AddResponse(elevenlabs.StreamingOutputResponse{Alignment: segment("Hello there. Bye")})
+	if got := out.String(); got != "1\n00:00:00,000 --> 00:00:00,120\nHello there.\n\n" {
+		t.Errorf("after the first sentence:\n%s", got)
+	}
+	w.AddResponse(elevenlabs.StreamingOutputResponse{IsFinal: true})
+	w.Close()
+
+	want := "1\n00:00:00,000 --> 00:00:00,120\nHello there.\n\n" +
+		"2\n00:00:00,130 --> 00:00:00,160\nBye\n\n"
+	if got := out.String(); got != want {
+		t.Errorf("file:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWebVTT(t *testing.T) {
+	var out bytes.Buffer
+	cues := []subtitles.Cue{{Index: 1, Start: 3723456 * time.Millisecond, End: 3724000 * time.Millisecond, Text: "one\ntwo"}}
+	if err := subtitles.WebVTT.Encode(&out, cues); err != nil {
+		t.Fatal(err)
+	}
+	want := "WEBVTT\n\n1\n01:02:03.456 --> 01:02:04.000\none\ntwo\n\n"
+	if got := out.String(); got != want {
+		t.Errorf("file:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The header is written even when no cue completes
+	out.Reset()
+	subtitles.NewWriter(&out, subtitles.WebVTT).Close()
+	if got := out.String(); got != "WEBVTT\n\n" {
+		t.Errorf("empty file = %q", got)
+	}
+}
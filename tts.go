@@ -0,0 +1,155 @@
+package elevenlabs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	neturl "net/url"
+)
+
+type TextToSpeechRequest struct {
+	Text          string         `json:"text"`
+	LanguageCode  string         `json:"language_code,omitempty"`
+	VoiceSettings *VoiceSettings `json:"voice_settings,omitempty"`
+	Seed          int            `json:"seed,omitempty"`
+	PreviousText  string         `json:"previous_text,omitempty"` // Context for continuity across prompts
+	NextText      string         `json:"next_text,omitempty"`
+
+	PronunciationDictionaryLocators []PronunciationDictionaryLocators `json:"pronunciation_dictionary_locators,omitempty"`
+}
+
+// Result of an HTTP text-to-speech call, Audio must be closed
+type TextToSpeechResponse struct {
+	Audio               io.ReadCloser
+	Format              AudioFormatInfo
+	Alignment           StreamingAlignmentSegment // Empty unless requested with timestamps
+	NormalizedAlignment StreamingAlignmentSegment
+	Meta                *ResponseMeta
+}
+
+// Alignment in the shape delivered on websocket alignment channels
+func (r *TextToSpeechResponse) Output() StreamingOutputResponse {
+	return StreamingOutputResponse{IsFinal: true, Alignment: r.Alignment, NormalizedAlignment: r.NormalizedAlignment}
+}
+
+// Whole clip read into memory, the request timeout covers the body
+func (c *Client) TextToSpeech(voiceID string, modelID string, req TextToSpeechRequest, queries ...QueryFunc) (*TextToSpeechResponse, error) {
+	res, err := c.textToSpeech("", false, voiceID, modelID, req, queries)
+	if err != nil {
+		return nil, err
+	}
+	// The timeout keeps running until the body is closed, consumers reading at playback pace would hit it
+	audio, err := io.ReadAll(res.Audio)
+	res.Audio.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading audio: %w", err)
+	}
+	res.Audio = io.NopCloser(bytes.NewReader(audio))
+	return res, nil
+}
+
+// Audio streamed as it is generated, the request timeout only covers the response headers
+func (c *Client) TextToSpeechStream(voiceID string, modelID string, req TextToSpeechRequest, queries ...QueryFunc) (*TextToSpeechResponse, error) {
+	return c.textToSpeech("/stream", true, voiceID, modelID, req, queries)
+}
+
+// Whole clip with character alignment, Audio reads from memory
+func (c *Client) TextToSpeechWithTimestamps(voiceID string, modelID string, req TextToSpeechRequest, queries ...QueryFunc) (*TextToSpeechResponse, error) {
+	res, err := c.textToSpeech("/with-timestamps", false, voiceID, modelID, req, queries)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Audio.Close()
+
+	var body struct {
+		AudioBase64         string            `json:"audio_base64"`
+		Alignment           *timestampSegment `json:"alignment"`
+		NormalizedAlignment *timestampSegment `json:"normalized_alignment"`
+	}
+	if err := json.NewDecoder(res.Audio).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	audio, err := base64.StdEncoding.DecodeString(body.AudioBase64)
+	if err != nil {
+		return nil, fmt.Errorf("decoding audio: %w", err)
+	}
+	res.Audio = io.NopCloser(bytes.NewReader(audio))
+	res.Alignment = body.Alignment.segment()
+	res.NormalizedAlignment = body.NormalizedAlignment.segment()
+	return res, nil
+}
+
+func (c *Client) textToSpeech(endpoint string, stream bool, voiceID string, modelID string, req TextToSpeechRequest, queries []QueryFunc) (*TextToSpeechResponse, error) {
+	tenant := c.opts.tenantFor(c.apiKey)
+	if err := c.opts.quota.Check(tenant); err != nil {
+		return nil, err
+	}
+	format, err := ResolveAudioFormat(queries...)
+	if err != nil {
+		return nil, err
+	}
+	if req.PronunciationDictionaryLocators == nil {
+		req.PronunciationDictionaryLocators = c.opts.dictionaries
+	}
+	if n := len(req.PronunciationDictionaryLocators); n > MAX_PRONUNCIATION_DICTIONARIES {
+		return nil, fmt.Errorf("%d pronunciation dictionaries, at most %d allowed", n, MAX_PRONUNCIATION_DICTIONARIES)
+	}
+
+	in := struct {
+		TextToSpeechRequest
+		ModelID string `json:"model_id,omitempty"`
+	}{req, modelID}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("encoding request: %w", err)
+	}
+
+	q := neturl.Values{}
+	for _, qf := range queries {
+		qf(&q)
+	}
+	path := fmt.Sprintf("/text-to-speech/%s%s", neturl.PathEscape(voiceID), endpoint)
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	// Generations are billed once the server accepts them, so only 429s and failed dials are retried
+	r := restRequest{method: http.MethodPost, path: path, contentType: JSON_CONTENT_TYPE, body: body, stream: stream}
+	resp, meta, err := c.send(c.ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	chars := meta.CharacterCost
+	if chars < 0 {
+		chars = billedChars(req.Text)
+	}
+	c.opts.quota.Add(tenant, chars)
+	return &TextToSpeechResponse{Audio: resp.Body, Format: format, Meta: meta}, nil
+}
+
+// Alignment as sent by the HTTP endpoints, in seconds
+type timestampSegment struct {
+	Characters                 []string  `json:"characters"`
+	CharacterStartTimesSeconds []float64 `json:"character_start_times_seconds"`
+	CharacterEndTimesSeconds   []float64 `json:"character_end_times_seconds"`
+}
+
+func (t *timestampSegment) segment() StreamingAlignmentSegment {
+	var s StreamingAlignmentSegment
+	if t == nil {
+		return s
+	}
+	n := min(len(t.Characters), len(t.CharacterStartTimesSeconds), len(t.CharacterEndTimesSeconds))
+	for i := range n {
+		start := int(math.Round(t.CharacterStartTimesSeconds[i] * 1000))
+		end := int(math.Round(t.CharacterEndTimesSeconds[i] * 1000))
+		s.Chars = append(s.Chars, t.Characters[i])
+		s.CharStartTimesMs = append(s.CharStartTimesMs, start)
+		s.CharDurationsMs = append(s.CharDurationsMs, max(end-start, 0))
+	}
+	return s
+}
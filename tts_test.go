@@ -0,0 +1,167 @@
+package elevenlabs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+// Handler writing audio in two parts, the second one after delay
+func slowAudio(delay time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "first ")
+		w.(http.Flusher).Flush()
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		io.WriteString(w, "second")
+	}
+}
+
+func TestTextToSpeechRequest(t *testing.T) {
+	var got struct {
+		Text     string                                       `json:"text"`
+		ModelID  string                                       `json:"model_id"`
+		Seed     int                                          `json:"seed"`
+		Locators []elevenlabs.PronunciationDictionaryLocators `json:"pronunciation_dictionary_locators"`
+	}
+	var path, query string
+	srv, _ := scripted(t, func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.EscapedPath(), r.URL.RawQuery
+		json.NewDecoder(r.Body).Decode(&got)
+		io.WriteString(w, "audio")
+	})
+	dict := elevenlabs.PronunciationDictionaryLocators{DictionaryId: "dict"}
+	c := restClient(t, srv.URL, elevenlabs.WithPronunciationDictionaries(dict))
+
+	res, err := c.TextToSpeech("voice/1", "model", elevenlabs.TextToSpeechRequest{Text: "Hi", Seed: 4}, elevenlabs.FormatULAW_8000.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, _ := io.ReadAll(res.Audio)
+	res.Audio.Close()
+
+	if string(audio) != "audio" || res.Format.Format != elevenlabs.FormatULAW_8000 || res.Meta == nil {
+		t.Errorf("response audio %q, format %s, meta %+v", audio, res.Format.Format, res.Meta)
+	}
+	if path != "/text-to-speech/voice%2F1" || query != "output_format=ulaw_8000" {
+		t.Errorf("request to %s?%s", path, query)
+	}
+	if got.Text != "Hi" || got.ModelID != "model" || got.Seed != 4 || len(got.Locators) != 1 || got.Locators[0] != dict {
+		t.Errorf("request body = %+v", got)
+	}
+
+	tooMany := make([]elevenlabs.PronunciationDictionaryLocators, elevenlabs.MAX_PRONUNCIATION_DICTIONARIES+1)
+	if _, err := c.TextToSpeech("voice", "model", elevenlabs.TextToSpeechRequest{Text: "Hi", PronunciationDictionaryLocators: tooMany}); err == nil {
+		t.Error("too many dictionaries accepted")
+	}
+}
+
+// The timeout only bounds the call, the returned clip can be read at any pace
+func TestTextToSpeechBuffersClip(t *testing.T) {
+	srv, _ := scripted(t, slowAudio(20*time.Millisecond))
+	c := elevenlabs.NewClient(context.Background(), "key", 200*time.Millisecond, elevenlabs.WithBaseURL(srv.URL))
+
+	res, err := c.TextToSpeech("voice", "model", elevenlabs.TextToSpeechRequest{Text: "Hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	audio, err := io.ReadAll(res.Audio)
+	if err != nil || string(audio) != "first second" {
+		t.Errorf("audio = %q, %v", audio, err)
+	}
+}
+
+func TestTextToSpeechStreamOutlivesTimeout(t *testing.T) {
+	srv, _ := scripted(t, slowAudio(200*time.Millisecond))
+	c := elevenlabs.NewClient(context.Background(), "key", 100*time.Millisecond, elevenlabs.WithBaseURL(srv.URL))
+
+	res, err := c.TextToSpeechStream("voice", "model", elevenlabs.TextToSpeechRequest{Text: "Hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Audio.Close()
+	audio, err := io.ReadAll(res.Audio)
+	if err != nil || string(audio) != "first second" {
+		t.Errorf("audio = %q, %v", audio, err)
+	}
+}
+
+func TestTextToSpeechWithTimestamps(t *testing.T) {
+	srv, _ := scripted(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/with-timestamps") {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `{
+			"audio_base64": "YXVkaW8=",
+			"alignment": {"characters": ["H", "i"], "character_start_times_seconds": [0, 0.1234], "character_end_times_seconds": [0.1234, 0.3]},
+			"normalized_alignment": {"characters": ["h"], "character_start_times_seconds": [0], "character_end_times_seconds": [0.05]}
+		}`)
+	})
+
+	res, err := restClient(t, srv.URL).TextToSpeechWithTimestamps("voice", "model", elevenlabs.TextToSpeechRequest{Text: "Hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, _ := io.ReadAll(res.Audio)
+	if string(audio) != "audio" {
+		t.Errorf("audio = %q", audio)
+	}
+	a := res.Alignment
+	if strings.Join(a.Chars, "") != "Hi" || a.CharStartTimesMs[1] != 123 || a.CharDurationsMs[0] != 123 || a.CharDurationsMs[1] != 177 {
+		t.Errorf("alignment = %+v", a)
+	}
+	if out := res.Output(); !out.IsFinal || len(out.NormalizedAlignment.Chars) != 1 || out.NormalizedAlignment.CharDurationsMs[0] != 50 {
+		t.Errorf("Output = %+v", out)
+	}
+}
+
+// Generations are billed once accepted, a failed one is not sent again
+func TestTextToSpeechDoesNotRetryServerErrors(t *testing.T) {
+	srv, calls := scripted(t, status(http.StatusBadGateway), body(http.StatusOK, "audio"))
+	_, err := restClient(t, srv.URL).TextToSpeech("voice", "model", elevenlabs.TextToSpeechRequest{Text: "Hi"})
+
+	var apiErr *elevenlabs.APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadGateway {
+		t.Errorf("TextToSpeech = %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("%d calls", n)
+	}
+}
+
+func TestTextToSpeechQuota(t *testing.T) {
+	srv, _ := scripted(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("character-cost", "40")
+			io.WriteString(w, "audio")
+		},
+		body(http.StatusOK, "audio"),
+	)
+	q := elevenlabs.NewQuotaTracker(elevenlabs.WithDefaultBudget(elevenlabs.Budget{DailyHard: 45}))
+	c := restClient(t, srv.URL, elevenlabs.WithQuota(q), elevenlabs.WithTenant("acme"))
+
+	// The reported cost wins over the text length, which is used without it
+	for _, text := range []string{"Hi", "Hello"} {
+		if _, err := c.TextToSpeech("voice", "model", elevenlabs.TextToSpeechRequest{Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if u := q.Usage("acme"); u.Daily != 45 {
+		t.Errorf("daily usage = %d, want 45", u.Daily)
+	}
+	if _, err := c.TextToSpeech("voice", "model", elevenlabs.TextToSpeechRequest{Text: "Hi"}); !errors.Is(err, elevenlabs.ErrBudgetExceeded) {
+		t.Errorf("TextToSpeech over budget = %v", err)
+	}
+}
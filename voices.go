@@ -0,0 +1,111 @@
+package elevenlabs
+
+import (
+	"fmt"
+	"iter"
+	"net/http"
+	neturl "net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// One page of the shared voice library, params.Page selects the page
+func (c *Client) SharedVoices(params ListVoicesParams) (*ListVoicesResponse, error) {
+	var r ListVoicesResponse
+	if err := c.doJSON(http.MethodGet, "/shared-voices?"+encodeQuery(params).Encode(), nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Every shared voice matching params, pages are fetched as the iteration advances starting at params.Page
+func (c *Client) AllSharedVoices(params ListVoicesParams) iter.Seq2[Voice, error] {
+	return func(yield func(Voice, error) bool) {
+		for {
+			page, err := c.SharedVoices(params)
+			if err != nil {
+				yield(Voice{}, err)
+				return
+			}
+			for _, v := range page.Voices {
+				if !yield(v, nil) {
+					return
+				}
+			}
+			if !page.HasMore || len(page.Voices) == 0 {
+				return
+			}
+			params.Page++
+		}
+	}
+}
+
+type ListOwnVoicesResponse struct {
+	Voices []GetVoiceVoice `json:"voices"`
+}
+
+// Voices in the account's library, including premade ones
+func (c *Client) Voices() ([]GetVoiceVoice, error) {
+	var r ListOwnVoicesResponse
+	if err := c.doJSON(http.MethodGet, "/voices", nil, &r); err != nil {
+		return nil, err
+	}
+	return r.Voices, nil
+}
+
+func (c *Client) GetVoiceSettings(voiceID string) (*GetVoiceSettings, error) {
+	var r GetVoiceSettings
+	if err := c.doJSON(http.MethodGet, fmt.Sprintf("/voices/%s/settings", neturl.PathEscape(voiceID)), nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Settings used for voices without their own
+func (c *Client) GetDefaultVoiceSettings() (*GetVoiceSettings, error) {
+	var r GetVoiceSettings
+	if err := c.doJSON(http.MethodGet, "/voices/settings/default", nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Store new default settings for a voice
+func (c *Client) EditVoiceSettings(voiceID string, settings GetVoiceSettings) error {
+	return c.doJSON(http.MethodPost, fmt.Sprintf("/voices/%s/settings/edit", neturl.PathEscape(voiceID)), settings, nil)
+}
+
+// Query values from the url tags of a struct, omitempty skips zero values
+func encodeQuery(v any) neturl.Values {
+	q := neturl.Values{}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		tag := rt.Field(i).Tag.Get("url")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := rv.Field(i)
+		if opts == "omitempty" && fv.IsZero() {
+			continue
+		}
+
+		switch fv.Kind() {
+		case reflect.String:
+			q.Set(name, fv.String())
+		case reflect.Bool:
+			q.Set(name, strconv.FormatBool(fv.Bool()))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			q.Set(name, strconv.FormatInt(fv.Int(), 10))
+		case reflect.Float32, reflect.Float64:
+			q.Set(name, strconv.FormatFloat(fv.Float(), 'f', -1, 64))
+		case reflect.Slice:
+			for j := range fv.Len() {
+				q.Add(name, fmt.Sprint(fv.Index(j).Interface()))
+			}
+		}
+	}
+	return q
+}
@@ -0,0 +1,117 @@
+package elevenlabs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEncodeQuery(t *testing.T) {
+	type params struct {
+		Name     string   `url:"name"`
+		Empty    string   `url:"empty,omitempty"`
+		Zero     int      `url:"zero"`
+		Count    int      `url:"count,omitempty"`
+		Ratio    float64  `url:"ratio,omitempty"`
+		Flag     bool     `url:"flag,omitempty"`
+		Off      bool     `url:"off,omitempty"`
+		Tags     []string `url:"tag,omitempty"`
+		Skipped  string   `url:"-"`
+		Untagged string
+	}
+	q := encodeQuery(&params{Name: "a b", Count: 3, Ratio: 0.25, Flag: true, Tags: []string{"x", "y"}, Skipped: "s", Untagged: "u"})
+
+	want := "count=3&flag=true&name=a+b&ratio=0.25&tag=x&tag=y&zero=0"
+	if got := q.Encode(); got != want {
+		t.Errorf("encodeQuery = %s, want %s", got, want)
+	}
+}
+
+func TestAllSharedVoices(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		page := r.URL.Query().Get("page")
+		fmt.Fprintf(w, `{"voices":[{"voice_id":"p%s-1"},{"voice_id":"p%s-2"}],"has_more":%v}`, page, page, page != "3")
+	}))
+	defer srv.Close()
+	c := NewClient(context.Background(), "key", time.Second, WithBaseURL(srv.URL))
+
+	var ids []string
+	for v, err := range c.AllSharedVoices(ListVoicesParams{PageSize: 2, Gender: "female", Featured: true, Page: 2}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, v.VoiceID)
+	}
+	if fmt.Sprint(ids) != "[p2-1 p2-2 p3-1 p3-2]" {
+		t.Errorf("voices = %v", ids)
+	}
+	if len(queries) != 2 || queries[0] != "featured=true&gender=female&page=2&page_size=2" {
+		t.Errorf("queries = %q", queries)
+	}
+
+	// Stopping early fetches no further pages
+	queries = nil
+	for range c.AllSharedVoices(ListVoicesParams{}) {
+		break
+	}
+	if len(queries) != 1 {
+		t.Errorf("%d pages fetched for one voice", len(queries))
+	}
+}
+
+func TestAllSharedVoicesError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"detail":{"status":"invalid_api_key","message":"bad key"}}`)
+	}))
+	defer srv.Close()
+	c := NewClient(context.Background(), "key", time.Second, WithBaseURL(srv.URL))
+
+	var errs []error
+	for _, err := range c.AllSharedVoices(ListVoicesParams{}) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrInvalidAPIKey) {
+		t.Errorf("errors = %v", errs)
+	}
+}
+
+func TestVoiceSettingsEndpoints(t *testing.T) {
+	var edited GetVoiceSettings
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "GET /voices/a%2Fb/settings":
+			fmt.Fprint(w, `{"stability":0.4,"similarity_boost":0.7,"speed":1.1}`)
+		case "GET /voices/settings/default":
+			fmt.Fprint(w, `{"stability":0.5}`)
+		case "POST /voices/v/settings/edit":
+			json.NewDecoder(r.Body).Decode(&edited)
+		case "GET /voices":
+			fmt.Fprint(w, `{"voices":[{"voice_id":"own"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := NewClient(context.Background(), "key", time.Second, WithBaseURL(srv.URL))
+
+	if s, err := c.GetVoiceSettings("a/b"); err != nil || s.Stability != 0.4 || s.Speed != 1.1 {
+		t.Errorf("GetVoiceSettings = %+v, %v", s, err)
+	}
+	if s, err := c.GetDefaultVoiceSettings(); err != nil || s.Stability != 0.5 {
+		t.Errorf("GetDefaultVoiceSettings = %+v, %v", s, err)
+	}
+	if err := c.EditVoiceSettings("v", GetVoiceSettings{Style: 0.3, UseSpeakerBoost: true}); err != nil || edited.Style != 0.3 || !edited.UseSpeakerBoost {
+		t.Errorf("EditVoiceSettings sent %+v, %v", edited, err)
+	}
+	if v, err := c.Voices(); err != nil || len(v) != 1 || v[0].VoiceID != "own" {
+		t.Errorf("Voices = %+v, %v", v, err)
+	}
+}
@@ -0,0 +1,221 @@
+// Package wav stores streaming TTS audio as playable WAV files.
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+)
+
+const HEADER_SIZE = 44
+
+// WAVE format tags
+const (
+	FORMAT_PCM  = 1
+	FORMAT_ALAW = 6
+	FORMAT_ULAW = 7
+)
+
+// Canonical 44 byte header for dataSize bytes of audio
+func Header(info elevenlabs.AudioFormatInfo, dataSize int) ([]byte, error) {
+	var tag uint16
+	switch info.Encoding {
+	case elevenlabs.EncodingPCM:
+		tag = FORMAT_PCM
+	case elevenlabs.EncodingALAW:
+		tag = FORMAT_ALAW
+	case elevenlabs.EncodingULAW:
+		tag = FORMAT_ULAW
+	default:
+		return nil, fmt.Errorf("audio format %q cannot be stored as WAV", info.Format)
+	}
+
+	h := make([]byte, 0, HEADER_SIZE)
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(HEADER_SIZE-8+dataSize+dataSize%2))
+	h = append(h, "WAVEfmt "...)
+	h = binary.LittleEndian.AppendUint32(h, 16)
+	h = binary.LittleEndian.AppendUint16(h, tag)
+	h = binary.LittleEndian.AppendUint16(h, uint16(info.Channels))
+	h = binary.LittleEndian.AppendUint32(h, uint32(info.SampleRate))
+	h = binary.LittleEndian.AppendUint32(h, uint32(info.BytesPerSecond()))
+	h = binary.LittleEndian.AppendUint16(h, uint16(info.FrameSize()))
+	h = binary.LittleEndian.AppendUint16(h, uint16(info.BitsPerSample))
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(dataSize))
+	return h, nil
+}
+
+// AudioResponsePipe writing a WAV file, the RIFF sizes are patched on Close.
+// The streaming request reports the format through SetAudioFormat before the first write.
+type Writer struct {
+	w      io.WriteSeeker
+	mu     sync.Mutex
+	format elevenlabs.AudioFormatInfo
+	set    bool
+	header bool // Placeholder header written
+	size   int
+	err    error
+}
+
+// Writer storing to w, the caller keeps ownership of w
+func NewWriter(w io.WriteSeeker) *Writer {
+	return &Writer{w: w}
+}
+
+func (w *Writer) SetAudioFormat(info elevenlabs.AudioFormatInfo) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.header {
+		if info != w.format {
+			w.err = fmt.Errorf("audio format changed from %q to %q", w.format.Format, info.Format)
+		}
+		return
+	}
+	w.format, w.set = info, true
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writeHeader(); err != nil {
+		return 0, err
+	}
+	n, err := w.w.Write(p)
+	w.size += n
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// Patch the header sizes and pad the data chunk, w is not closed
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	if w.size%2 == 1 {
+		if _, err := w.w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+
+	header, err := Header(w.format, w.size)
+	if err != nil {
+		return err
+	}
+	end, err := w.w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Seek(end-int64(HEADER_SIZE+w.size+w.size%2), io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(header); err != nil {
+		return err
+	}
+	_, err = w.w.Seek(end, io.SeekStart)
+	w.err = fmt.Errorf("wav writer closed")
+	return err
+}
+
+func (w *Writer) writeHeader() error {
+	if w.err != nil || w.header {
+		return w.err
+	}
+	if !w.set {
+		return fmt.Errorf("audio format not set")
+	}
+	header, err := Header(w.format, 0)
+	if err != nil {
+		w.err = err
+		return err
+	}
+	if _, err := w.w.Write(header); err != nil {
+		w.err = err
+		return err
+	}
+	w.header = true
+	return nil
+}
+
+// In-memory AudioResponsePipe, Bytes returns a complete WAV file at any point
+type Buffer struct {
+	mu     sync.Mutex
+	format elevenlabs.AudioFormatInfo
+	data   bytes.Buffer
+}
+
+// Buffer for format, SetAudioFormat replaces it when used as a pipe
+func NewBuffer(format elevenlabs.AudioFormatInfo) *Buffer {
+	return &Buffer{format: format}
+}
+
+func (b *Buffer) SetAudioFormat(info elevenlabs.AudioFormatInfo) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.format = info
+}
+
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data.Write(p)
+}
+
+func (b *Buffer) Format() elevenlabs.AudioFormatInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.format
+}
+
+// Raw audio written so far, without header
+func (b *Buffer) Audio() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.data.Bytes())
+}
+
+// WAV file of the audio written so far
+func (b *Buffer) Bytes() ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	size := b.data.Len()
+	header, err := Header(b.format, size)
+	if err != nil {
+		return nil, err
+	}
+	file := make([]byte, 0, HEADER_SIZE+size+size%2)
+	file = append(file, header...)
+	file = append(file, b.data.Bytes()...)
+	if size%2 == 1 {
+		file = append(file, 0)
+	}
+	return file, nil
+}
+
+// Write the WAV file to w
+func (b *Buffer) WriteTo(w io.Writer) (int64, error) {
+	file, err := b.Bytes()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(file)
+	return int64(n), err
+}
+
+func (b *Buffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data.Reset()
+}
@@ -0,0 +1,154 @@
+package wav_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	elevenlabs "github.com/clearlyip/elevenlabs-go-websockets"
+	"github.com/clearlyip/elevenlabs-go-websockets/wav"
+)
+
+func format(t *testing.T, f elevenlabs.AudioFormat) elevenlabs.AudioFormatInfo {
+	t.Helper()
+	info, err := f.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+// Check the header fields of a WAV file holding data
+func checkFile(t *testing.T, file []byte, tag uint16, rate uint32, bits uint16, data []byte) {
+	t.Helper()
+	if len(file) < wav.HEADER_SIZE {
+		t.Fatalf("%d byte file", len(file))
+	}
+	le := binary.LittleEndian
+	if string(file[0:4]) != "RIFF" || string(file[8:16]) != "WAVEfmt " || string(file[36:40]) != "data" {
+		t.Fatalf("bad chunk ids in % x", file[:wav.HEADER_SIZE])
+	}
+	padded := len(data) + len(data)%2
+	if got := le.Uint32(file[4:]); got != uint32(wav.HEADER_SIZE-8+padded) {
+		t.Errorf("RIFF size = %d", got)
+	}
+	if got := le.Uint16(file[20:]); got != tag {
+		t.Errorf("format tag = %d, want %d", got, tag)
+	}
+	if got := le.Uint32(file[24:]); got != rate {
+		t.Errorf("sample rate = %d, want %d", got, rate)
+	}
+	if got := le.Uint32(file[28:]); got != rate*uint32(bits/8) {
+		t.Errorf("byte rate = %d", got)
+	}
+	if got := le.Uint16(file[34:]); got != bits {
+		t.Errorf("bits per sample = %d, want %d", got, bits)
+	}
+	if got := le.Uint32(file[40:]); got != uint32(len(data)) {
+		t.Errorf("data size = %d, want %d", got, len(data))
+	}
+	if len(file) != wav.HEADER_SIZE+padded || !bytes.Equal(file[wav.HEADER_SIZE:][:len(data)], data) {
+		t.Errorf("data = % x", file[wav.HEADER_SIZE:])
+	}
+}
+
+func TestWriterPatchesHeaderOnClose(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := wav.NewWriter(f)
+	w.SetAudioFormat(format(t, elevenlabs.FormatPCM_16000))
+	data := []byte{1, 2, 3, 4, 5, 6}
+	w.Write(data[:3])
+	w.Write(data[3:])
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+
+	f.Seek(0, io.SeekStart)
+	file, _ := io.ReadAll(f)
+	checkFile(t, file, wav.FORMAT_PCM, 16000, 16, data)
+}
+
+func TestWriterOddSizeAfterExistingContent(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Write([]byte("prefix"))
+
+	w := wav.NewWriter(f)
+	w.SetAudioFormat(format(t, elevenlabs.FormatULAW_8000))
+	data := []byte{0xFF, 0x7F, 0x00}
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Seek(0, io.SeekStart)
+	file, _ := io.ReadAll(f)
+	if string(file[:6]) != "prefix" {
+		t.Fatalf("existing content overwritten: %q", file[:6])
+	}
+	checkFile(t, file[6:], wav.FORMAT_ULAW, 8000, 8, data)
+}
+
+func TestWriterRejectsFormatChanges(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := wav.NewWriter(f)
+	if _, err := w.Write([]byte{1}); err == nil {
+		t.Error("Write before SetAudioFormat succeeded")
+	}
+	w.SetAudioFormat(format(t, elevenlabs.FormatPCM_24000))
+	w.Write([]byte{1, 2})
+	w.SetAudioFormat(format(t, elevenlabs.FormatPCM_16000))
+	if _, err := w.Write([]byte{3, 4}); err == nil {
+		t.Error("Write after a format change succeeded")
+	}
+
+	mp3 := wav.NewWriter(f)
+	mp3.SetAudioFormat(format(t, elevenlabs.FormatMP3_44100_128))
+	if _, err := mp3.Write([]byte{1}); err == nil {
+		t.Error("mp3 audio accepted")
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	b := wav.NewBuffer(format(t, elevenlabs.FormatPCM_22050))
+	b.SetAudioFormat(format(t, elevenlabs.FormatALAW_8000))
+	data := []byte{0xD5, 0xD5, 0x55}
+	b.Write(data)
+
+	file, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, file, wav.FORMAT_ALAW, 8000, 8, data)
+	if !bytes.Equal(b.Audio(), data) {
+		t.Errorf("Audio = % x", b.Audio())
+	}
+
+	var out bytes.Buffer
+	if n, err := b.WriteTo(&out); err != nil || n != int64(len(file)) || !bytes.Equal(out.Bytes(), file) {
+		t.Errorf("WriteTo = %d, %v", n, err)
+	}
+
+	b.Reset()
+	file, _ = b.Bytes()
+	checkFile(t, file, wav.FORMAT_ALAW, 8000, 8, nil)
+}